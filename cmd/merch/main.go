@@ -1,66 +1,146 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"merch/internal/config"
+	"merch/internal/domain"
+	"merch/internal/interfaces"
 	"merch/internal/presentation/postgres"
 	"merch/internal/presentation/realization"
 	"merch/internal/presentation/server"
 	"merch/internal/services"
 	"os"
-
-	"github.com/joho/godotenv"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
 func main() {
-	logger, err := realization.NewLogger()
+	cfg, err := config.Load(os.Args[1:])
 	if err != nil {
 		fmt.Println(err)
 		return
 	}
 
-	err = godotenv.Load("../../../.env")
+	logger, err := realization.NewLogger(cfg.Log.Level, cfg.Log.Format)
 	if err != nil {
-		logger.Error("Failed to load env fail")
+		fmt.Println(err)
 		return
 	}
+	logger.Info("Configuration loaded", domain.Field("config", cfg.String()))
 
-	host := os.Getenv("DB_HOST")
-	port := os.Getenv("DB_PORT")
-	user := os.Getenv("DB_USER")
-	password := os.Getenv("DB_PASSWORD")
-	name := os.Getenv("DB_NAME")
-
-	db, err := postgres.CreateDB(host, port, user, password, name, logger)
+	tracing, err := realization.NewTracing(context.Background(), cfg.Tracing.Exporter, cfg.Tracing.Endpoint, cfg.Tracing.ServiceName)
 	if err != nil {
 		logger.Error(err.Error())
 		return
 	}
+	defer func() {
+		// Отправляем оставшиеся спаны после остановки сервера
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+		defer cancel()
 
-	err = db.CreateSchema()
+		err := tracing.Shutdown(ctx)
+		if err != nil {
+			logger.Error("Tracing shutdown error", domain.Field("error", err.Error()))
+		}
+	}()
+
+	db, err := postgres.CreateDB(cfg.Db.Host, cfg.Db.Port, cfg.Db.User, cfg.Db.Password, cfg.Db.Name, cfg.Db.Options(), logger, tracing.Provider)
 	if err != nil {
 		logger.Error(err.Error())
 		return
 	}
 
-	userRepo := realization.NewUser()
-	transactionRepo := realization.NewTransaction()
-	inventoryRepo := realization.NewInventory()
-	moneyService := services.NewMoneyService(userRepo, transactionRepo, inventoryRepo)
+	if cfg.Db.SkipMigrate {
+		logger.Info("Skipping migrations and seeds on startup", domain.Field("expectedVersion", postgres.SCHEMA_VERSION))
+	} else {
+		err = db.CreateSchema()
+		if err != nil {
+			logger.Error(err.Error())
+			return
+		}
+
+		err = db.Seed(context.Background(), cfg.Db.Seeds...)
+		if err != nil {
+			return
+		}
+	}
+
+	// Запросы на чтение для /api/info уходят на реплики, если они указаны
+	if len(cfg.Db.ReplicaHosts) != 0 {
+		replicas, err := postgres.ConnectReplicas(cfg.Db.ReplicaHosts, cfg.Db.Port, cfg.Db.User, cfg.Db.Password, cfg.Db.Name, cfg.Db.Options(), logger, tracing.Provider)
+		if err != nil {
+			logger.Error(err.Error())
+			return
+		}
 
-	secretKey := os.Getenv("SECRET_KEY")
-	authRepo := realization.NewAuth(secretKey)
-	userService := services.NewUserService(userRepo, authRepo, transactionRepo, inventoryRepo)
+		db.UseReplicas(replicas, cfg.Db.StickyWindow)
+	}
 
-	serverPort := os.Getenv("SERVER_PORT")
-	srv := server.NewServer()
-	err = srv.Start(moneyService, userService, secretKey, serverPort)
-	if err != nil {
-		logger.Error(fmt.Sprintf("Critical server error: %v", err))
+	queryTimeout := cfg.Db.QueryTimeout
+	metrics := realization.NewMetrics(db.Pools())
+
+	// Каталог и ответы /api/info кэшируются в памяти, записи сбрасывают сводки затронутых пользователей.
+	// С репликами сводки не кэшируются еще DB_STICKY_WINDOW после записи, пока реплики могут отдавать старые данные
+	var replicaLag time.Duration
+	if len(cfg.Db.ReplicaHosts) != 0 {
+		replicaLag = cfg.Db.StickyWindow
 	}
 
-	err = srv.Shutdown()
+	cache := realization.NewMemoryCache()
+	userRepo := realization.NewCachedUser(realization.NewUser(db.Db, db, logger, queryTimeout), cache, replicaLag, metrics, logger)
+	transactionRepo := realization.NewCachedTransaction(realization.NewTransaction(db.Db, db, logger, queryTimeout), userRepo, cache, replicaLag, metrics, logger)
+	inventoryRepo := realization.NewCachedInventory(realization.NewInventory(db.Db, db, logger, queryTimeout), cache, cfg.Cache.CatalogTTL, replicaLag, metrics, logger)
+	userInfoRepo := realization.NewCachedUserInfo(realization.NewUserInfo(db.Db, db, logger, queryTimeout), cache, cfg.Cache.UserInfoTTL, metrics, logger)
+	twoFactorRepo := realization.NewTwoFactor(db.Db, logger, queryTimeout)
+	moneyService := services.NewMoneyService(userRepo, transactionRepo, inventoryRepo, metrics, tracing.Provider)
+
+	authRepo := realization.NewAuth(db.Db, logger, queryTimeout, cfg.Auth.SecretKey)
+	userService := services.NewUserService(userRepo, authRepo, userInfoRepo, twoFactorRepo, metrics, tracing.Provider)
+	guardService := services.NewLoginGuardService(realization.NewLoginAttempt(db.Db, logger, queryTimeout))
+	keyService := services.NewApiKeyService(realization.NewApiKey(db.Db, logger, queryTimeout))
+	twoFactorService := services.NewTwoFactorService(twoFactorRepo, realization.NewTOTP("Merch"), authRepo)
+
+	// Вход через SSO включается, если указан издатель OIDC
+	var providers []interfaces.IdentityProvider
+	if cfg.OIDC.Issuer != "" {
+		provider, err := realization.NewOIDC(cfg.OIDC.Name, cfg.OIDC.Issuer, cfg.OIDC.ClientId, cfg.OIDC.ClientSecret, cfg.OIDC.RedirectURL, nil, logger)
+		if err != nil {
+			logger.Error(err.Error())
+			return
+		}
+
+		providers = append(providers, provider)
+	}
+
+	identityService := services.NewIdentityService(realization.NewExternalIdentity(db.Db, logger, queryTimeout), userRepo, authRepo, twoFactorRepo, providers...)
+
+	srv := server.NewServer(server.Deps{
+		Money:     moneyService,
+		User:      userService,
+		Guard:     guardService,
+		Keys:      keyService,
+		TwoFactor: twoFactorService,
+		Identity:  identityService,
+		Health:    services.NewHealthService(db, postgres.SCHEMA_VERSION),
+		Limiter:   realization.NewMemoryRateLimiter(),
+		Limits:    server.DefaultRateLimitConfig(),
+		Metrics:   metrics,
+		Tracer:    tracing.Provider,
+		Logger:    logger,
+		Db:        db,
+		Port:      cfg.Server.Port,
+		Drain:     cfg.Server.ShutdownTimeout,
+	})
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	err = srv.Run(ctx)
 	if err != nil {
-		logger.Error(fmt.Sprintf("Shutdown server error: %v", err))
+		logger.Error("Server error", domain.Field("error", err.Error()))
+		return
 	}
 
 	logger.Info("Graceful shutdown completed successfully")
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"merch/internal/config"
+	"merch/internal/domain"
+	"merch/internal/interfaces"
+	"merch/internal/presentation/postgres"
+	"merch/internal/presentation/realization"
+	"os"
+	"strconv"
+
+	"github.com/golang-migrate/migrate/v4"
+)
+
+// usage - описание команд утилиты
+const usage = `Usage: migrate [flags] <command> [argument]
+
+Commands:
+  up           apply all pending migrations
+  down [N]     roll back N migrations, all of them if N is omitted
+  goto V       migrate up or down to version V
+  version      print the current schema version
+  force V      set version V without running migrations, used to fix a dirty schema
+  seed [SET]   apply seed sets (catalog, demo), the ones from DB_SEEDS if omitted
+
+Flags are the same as for the merch server, see -help`
+
+func main() {
+	err := run(os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// run выполняет команду миграции или заполнения начальными данными с настройками подключения из конфигурации сервера
+func run(args []string) error {
+	cfg, args, err := config.LoadWithArgs(args)
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+
+	logger, err := realization.NewLogger(cfg.Log.Level, cfg.Log.Format)
+	if err != nil {
+		return err
+	}
+
+	db, err := postgres.CreateDB(cfg.Db.Host, cfg.Db.Port, cfg.Db.User, cfg.Db.Password, cfg.Db.Name, cfg.Db.Options(), logger, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = db.CloseDB()
+	}()
+
+	if args[0] == "seed" {
+		sets := args[1:]
+		if len(sets) == 0 {
+			sets = cfg.Db.Seeds
+		}
+
+		return db.Seed(context.Background(), sets...)
+	}
+
+	return migrateSchema(db, logger, args[0], args[1:])
+}
+
+// migrateSchema выполняет команду мигратора и выводит версию схемы после нее
+func migrateSchema(db *postgres.DB, logger interfaces.LoggerRepo, command string, args []string) error {
+	m, err := db.Migrator(context.Background())
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	err = apply(m, command, args)
+	if errors.Is(err, migrate.ErrNoChange) {
+		logger.Info("No migrations to apply")
+	} else if err != nil {
+		return fmt.Errorf("%s: %w", command, err)
+	}
+
+	version, dirty, err := m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		logger.Info("No migrations applied", domain.Field("latest", postgres.SCHEMA_VERSION))
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading version: %w", err)
+	}
+
+	logger.Info("Schema version", domain.Field("version", version), domain.Field("dirty", dirty), domain.Field("latest", postgres.SCHEMA_VERSION))
+	return nil
+}
+
+// apply выполняет команду мигратора
+func apply(m *migrate.Migrate, command string, args []string) error {
+	if command == "up" {
+		return m.Up()
+	}
+	if command == "version" {
+		return nil
+	}
+	if command == "down" && len(args) == 0 {
+		return m.Down()
+	}
+
+	number, err := parseNumber(args)
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "down":
+		return m.Steps(-number)
+	case "goto":
+		return m.Migrate(uint(number))
+	case "force":
+		return m.Force(number)
+	default:
+		return fmt.Errorf("unknown command %q\n\n%s", command, usage)
+	}
+}
+
+// parseNumber читает единственный аргумент команды как неотрицательное число
+func parseNumber(args []string) (int, error) {
+	if len(args) != 1 {
+		return 0, errors.New(usage)
+	}
+
+	number, err := strconv.Atoi(args[0])
+	if err != nil || number < 0 {
+		return 0, fmt.Errorf("invalid number %q", args[0])
+	}
+
+	return number, nil
+}
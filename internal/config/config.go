@@ -0,0 +1,513 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"merch/internal/domain"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// MIN_SECRET_KEY_LENGTH - минимальная длина ключа подписи JWT
+const MIN_SECRET_KEY_LENGTH = 32
+
+// ConfigError - ошибка загрузки или проверки конфигурации
+type ConfigError = domain.TypedError[configErrorTag]
+
+type configErrorTag struct{}
+
+// REDACTED - значение, которым заменяются секреты при выводе конфигурации
+const REDACTED = "[REDACTED]"
+
+// Значения по умолчанию
+const (
+	DEFAULT_DB_PORT          = "5432"
+	DEFAULT_SERVER_PORT      = "8080"
+	DEFAULT_QUERY_TIMEOUT    = 3 * time.Second
+	DEFAULT_MAX_OPEN_CONNS   = 25
+	DEFAULT_MAX_IDLE_CONNS   = 10
+	DEFAULT_CONN_LIFETIME    = 30 * time.Minute
+	DEFAULT_CONN_IDLE_TIME   = 5 * time.Minute
+	DEFAULT_SSL_MODE         = domain.SSL_DISABLE
+	DEFAULT_CONNECT_ATTEMPTS = 5
+	DEFAULT_CONNECT_BACKOFF  = time.Second
+	DEFAULT_STICKY_WINDOW    = 5 * time.Second
+	DEFAULT_SHUTDOWN_TIMEOUT = 10 * time.Second
+	DEFAULT_ENV_FILE         = ".env"
+	DEFAULT_TRACING_EXPORTER = domain.TRACING_NONE
+	DEFAULT_SERVICE_NAME     = "merch"
+	DEFAULT_LOG_LEVEL        = "info"
+	DEFAULT_LOG_FORMAT       = domain.LOG_FORMAT_JSON
+	DEFAULT_CATALOG_TTL      = 5 * time.Minute
+	DEFAULT_USER_INFO_TTL    = 5 * time.Second
+)
+
+// Config - конфигурация приложения
+type Config struct {
+	Db      DbConfig      `yaml:"db"`
+	Server  ServerConfig  `yaml:"server"`
+	Auth    AuthConfig    `yaml:"auth"`
+	OIDC    OIDCConfig    `yaml:"oidc"`
+	Tracing TracingConfig `yaml:"tracing"`
+	Log     LogConfig     `yaml:"log"`
+	Cache   CacheConfig   `yaml:"cache"`
+}
+
+// DbConfig - параметры подключения к базе данных
+type DbConfig struct {
+	Host         string        `yaml:"host"`          // Адрес сервера базы данных
+	Port         string        `yaml:"port"`          // Порт сервера базы данных
+	User         string        `yaml:"user"`          // Имя пользователя
+	Password     string        `yaml:"password"`      // Пароль пользователя
+	Name         string        `yaml:"name"`          // Имя базы данных
+	QueryTimeout time.Duration `yaml:"query_timeout"` // Время выполнения одного запроса
+	SkipMigrate  bool          `yaml:"skip_migrate"`  // Не применять миграции и начальные данные при запуске, например если их применяет cmd/migrate
+	Seeds        []string      `yaml:"seeds"`         // Наборы начальных данных, которые применяются после миграций
+
+	MaxOpenConns    int           `yaml:"max_open_conns"`     // Максимум открытых соединений
+	MaxIdleConns    int           `yaml:"max_idle_conns"`     // Максимум простаивающих соединений
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`  // Время жизни соединения
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time"` // Время простоя, после которого соединение закрывается
+
+	SSLMode     string `yaml:"ssl_mode"`      // disable, require, verify-ca или verify-full
+	SSLRootCert string `yaml:"ssl_root_cert"` // Путь к сертификату центра для проверки сервера
+	SSLCert     string `yaml:"ssl_cert"`      // Путь к клиентскому сертификату
+	SSLKey      string `yaml:"ssl_key"`       // Путь к ключу клиентского сертификата
+
+	StatementCache bool `yaml:"statement_cache"` // Кэшировать подготовленные запросы, выключается за PgBouncer в режиме transaction
+
+	ConnectAttempts int           `yaml:"connect_attempts"` // Сколько раз проверить подключение при запуске
+	ConnectBackoff  time.Duration `yaml:"connect_backoff"`  // Пауза перед повторной попыткой, затем удваивается
+
+	ReplicaHosts []string      `yaml:"replica_hosts"` // Реплики для запросов на чтение в виде host или host:port
+	StickyWindow time.Duration `yaml:"sticky_window"` // Сколько после перевода или покупки пользователь читает с основного сервера
+}
+
+// Options возвращает параметры пула соединений, TLS и подключения для postgres.CreateDB
+func (c DbConfig) Options() domain.DbOptions {
+	return domain.DbOptions{
+		MaxOpenConns:    c.MaxOpenConns,
+		MaxIdleConns:    c.MaxIdleConns,
+		ConnMaxLifetime: c.ConnMaxLifetime,
+		ConnMaxIdleTime: c.ConnMaxIdleTime,
+		SSLMode:         c.SSLMode,
+		SSLRootCert:     c.SSLRootCert,
+		SSLCert:         c.SSLCert,
+		SSLKey:          c.SSLKey,
+
+		NoStatementCache: !c.StatementCache,
+
+		ConnectAttempts: c.ConnectAttempts,
+		ConnectBackoff:  c.ConnectBackoff,
+	}
+}
+
+// ServerConfig - параметры http сервера
+type ServerConfig struct {
+	Port            string        `yaml:"port"`             // Порт, на котором сервер принимает запросы
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"` // Время ожидания завершения текущих запросов при остановке
+}
+
+// AuthConfig - параметры авторизации
+type AuthConfig struct {
+	SecretKey string `yaml:"secret_key"` // Ключ подписи JWT
+}
+
+// OIDCConfig - параметры входа через внешнего провайдера. Вход выключен, если Issuer не задан
+type OIDCConfig struct {
+	Issuer       string `yaml:"issuer"`        // Адрес издателя OIDC
+	Name         string `yaml:"name"`          // Имя провайдера в адресе входа
+	ClientId     string `yaml:"client_id"`     // Идентификатор клиента
+	ClientSecret string `yaml:"client_secret"` // Секрет клиента
+	RedirectURL  string `yaml:"redirect_url"`  // Адрес возврата после входа
+}
+
+// TracingConfig - параметры трассировки OpenTelemetry
+type TracingConfig struct {
+	Exporter    string `yaml:"exporter"`     // none, otlp или stdout
+	Endpoint    string `yaml:"endpoint"`     // Адрес коллектора OTLP/HTTP, например http://localhost:4318
+	ServiceName string `yaml:"service_name"` // Имя сервиса в трассах
+}
+
+// LogConfig - параметры логирования
+type LogConfig struct {
+	Level  string `yaml:"level"`  // Минимальный уровень записей: debug, info, warn или error
+	Format string `yaml:"format"` // json или console
+}
+
+// CacheConfig - время жизни записей кэша в памяти процесса, 0 выключает кэш
+type CacheConfig struct {
+	CatalogTTL  time.Duration `yaml:"catalog_ttl"`   // Сколько хранить предметы каталога
+	UserInfoTTL time.Duration `yaml:"user_info_ttl"` // Сколько хранить ответ /api/info, сбрасывается после перевода, покупки и начисления
+}
+
+// param описывает параметр, который можно задать переменной окружения и флагом командной строки
+type param struct {
+	env   string
+	flag  string
+	usage string
+	set   func(c *Config, value string) error
+}
+
+// params - параметры, доступные через переменные окружения и флаги
+var params = []param{
+	{"DB_HOST", "db-host", "database host", setString(func(c *Config) *string { return &c.Db.Host })},
+	{"DB_PORT", "db-port", "database port", setString(func(c *Config) *string { return &c.Db.Port })},
+	{"DB_USER", "db-user", "database user", setString(func(c *Config) *string { return &c.Db.User })},
+	{"DB_PASSWORD", "db-password", "database password", setString(func(c *Config) *string { return &c.Db.Password })},
+	{"DB_NAME", "db-name", "database name", setString(func(c *Config) *string { return &c.Db.Name })},
+	{"DB_QUERY_TIMEOUT", "db-query-timeout", "timeout of a single database query, e.g. 5s", setDuration(func(c *Config) *time.Duration { return &c.Db.QueryTimeout })},
+	{"DB_MAX_OPEN_CONNS", "db-max-open-conns", "maximum number of open database connections", setInt(func(c *Config) *int { return &c.Db.MaxOpenConns })},
+	{"DB_MAX_IDLE_CONNS", "db-max-idle-conns", "maximum number of idle database connections", setInt(func(c *Config) *int { return &c.Db.MaxIdleConns })},
+	{"DB_CONN_MAX_LIFETIME", "db-conn-max-lifetime", "maximum lifetime of a database connection, e.g. 30m", setDuration(func(c *Config) *time.Duration { return &c.Db.ConnMaxLifetime })},
+	{"DB_CONN_MAX_IDLE_TIME", "db-conn-max-idle-time", "maximum idle time of a database connection, e.g. 5m", setDuration(func(c *Config) *time.Duration { return &c.Db.ConnMaxIdleTime })},
+	{"DB_SSL_MODE", "db-ssl-mode", "database TLS mode: disable, require, verify-ca or verify-full", setString(func(c *Config) *string { return &c.Db.SSLMode })},
+	{"DB_SSL_ROOT_CERT", "db-ssl-root-cert", "path to the CA certificate of the database server", setString(func(c *Config) *string { return &c.Db.SSLRootCert })},
+	{"DB_SSL_CERT", "db-ssl-cert", "path to the database client certificate", setString(func(c *Config) *string { return &c.Db.SSLCert })},
+	{"DB_SSL_KEY", "db-ssl-key", "path to the database client certificate key", setString(func(c *Config) *string { return &c.Db.SSLKey })},
+	{"DB_STATEMENT_CACHE", "db-statement-cache", "cache prepared statements on each connection: true or false", setBool(func(c *Config) *bool { return &c.Db.StatementCache })},
+	{"DB_CONNECT_ATTEMPTS", "db-connect-attempts", "how many times to try connecting to the database on startup", setInt(func(c *Config) *int { return &c.Db.ConnectAttempts })},
+	{"DB_CONNECT_BACKOFF", "db-connect-backoff", "delay before the second connection attempt, doubled after each failure, e.g. 1s", setDuration(func(c *Config) *time.Duration { return &c.Db.ConnectBackoff })},
+	{"DB_REPLICA_HOSTS", "db-replica-hosts", "comma separated read replicas as host or host:port", setList(func(c *Config) *[]string { return &c.Db.ReplicaHosts })},
+	{"DB_STICKY_WINDOW", "db-sticky-window", "how long a user reads from the primary after a transfer or purchase, e.g. 5s", setDuration(func(c *Config) *time.Duration { return &c.Db.StickyWindow })},
+	{"DB_SKIP_MIGRATE", "db-skip-migrate", "do not apply migrations on startup: true or false", setBool(func(c *Config) *bool { return &c.Db.SkipMigrate })},
+	{"DB_SEEDS", "db-seeds", "comma separated seed sets applied after migrations, e.g. catalog,demo", setList(func(c *Config) *[]string { return &c.Db.Seeds })},
+	{"SERVER_PORT", "port", "http server port", setString(func(c *Config) *string { return &c.Server.Port })},
+	{"SHUTDOWN_TIMEOUT", "shutdown-timeout", "time to drain in-flight requests on shutdown, e.g. 30s", setDuration(func(c *Config) *time.Duration { return &c.Server.ShutdownTimeout })},
+	{"SECRET_KEY", "secret-key", "JWT signing key", setString(func(c *Config) *string { return &c.Auth.SecretKey })},
+	{"OIDC_ISSUER", "oidc-issuer", "OIDC issuer URL, enables SSO", setString(func(c *Config) *string { return &c.OIDC.Issuer })},
+	{"OIDC_NAME", "oidc-name", "OIDC provider name", setString(func(c *Config) *string { return &c.OIDC.Name })},
+	{"OIDC_CLIENT_ID", "oidc-client-id", "OIDC client id", setString(func(c *Config) *string { return &c.OIDC.ClientId })},
+	{"OIDC_CLIENT_SECRET", "oidc-client-secret", "OIDC client secret", setString(func(c *Config) *string { return &c.OIDC.ClientSecret })},
+	{"OIDC_REDIRECT_URL", "oidc-redirect-url", "OIDC redirect URL", setString(func(c *Config) *string { return &c.OIDC.RedirectURL })},
+	{"TRACING_EXPORTER", "tracing-exporter", "trace exporter: none, otlp or stdout", setString(func(c *Config) *string { return &c.Tracing.Exporter })},
+	{"TRACING_ENDPOINT", "tracing-endpoint", "OTLP/HTTP collector URL, e.g. http://localhost:4318", setString(func(c *Config) *string { return &c.Tracing.Endpoint })},
+	{"LOG_LEVEL", "log-level", "minimal log level: debug, info, warn or error", setString(func(c *Config) *string { return &c.Log.Level })},
+	{"LOG_FORMAT", "log-format", "log format: json or console", setString(func(c *Config) *string { return &c.Log.Format })},
+	{"CACHE_CATALOG_TTL", "cache-catalog-ttl", "how long catalog items are cached, 0 disables the cache, e.g. 5m", setDuration(func(c *Config) *time.Duration { return &c.Cache.CatalogTTL })},
+	{"CACHE_USER_INFO_TTL", "cache-user-info-ttl", "how long /api/info responses are cached, 0 disables the cache, e.g. 5s", setDuration(func(c *Config) *time.Duration { return &c.Cache.UserInfoTTL })},
+	{"TRACING_SERVICE_NAME", "tracing-service-name", "service name in traces", setString(func(c *Config) *string { return &c.Tracing.ServiceName })},
+}
+
+func setString(field func(c *Config) *string) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		*field(c) = value
+		return nil
+	}
+}
+
+func setInt(field func(c *Config) *int) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+
+		*field(c) = number
+		return nil
+	}
+}
+
+func setDuration(field func(c *Config) *time.Duration) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+
+		*field(c) = duration
+		return nil
+	}
+}
+
+func setBool(field func(c *Config) *bool) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+
+		*field(c) = b
+		return nil
+	}
+}
+
+func setList(field func(c *Config) *[]string) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		var list []string
+		for _, item := range strings.Split(value, ",") {
+			item = strings.TrimSpace(item)
+			if item != "" {
+				list = append(list, item)
+			}
+		}
+
+		*field(c) = list
+		return nil
+	}
+}
+
+// Default возвращает конфигурацию со значениями по умолчанию
+func Default() Config {
+	return Config{
+		Db: DbConfig{
+			Port:         DEFAULT_DB_PORT,
+			QueryTimeout: DEFAULT_QUERY_TIMEOUT,
+			Seeds:        []string{domain.SEED_CATALOG},
+
+			MaxOpenConns:    DEFAULT_MAX_OPEN_CONNS,
+			MaxIdleConns:    DEFAULT_MAX_IDLE_CONNS,
+			ConnMaxLifetime: DEFAULT_CONN_LIFETIME,
+			ConnMaxIdleTime: DEFAULT_CONN_IDLE_TIME,
+			SSLMode:         DEFAULT_SSL_MODE,
+			StatementCache:  true,
+			ConnectAttempts: DEFAULT_CONNECT_ATTEMPTS,
+			ConnectBackoff:  DEFAULT_CONNECT_BACKOFF,
+			StickyWindow:    DEFAULT_STICKY_WINDOW,
+		},
+		Server: ServerConfig{
+			Port:            DEFAULT_SERVER_PORT,
+			ShutdownTimeout: DEFAULT_SHUTDOWN_TIMEOUT,
+		},
+		OIDC: OIDCConfig{
+			Name: "oidc",
+		},
+		Tracing: TracingConfig{
+			Exporter:    DEFAULT_TRACING_EXPORTER,
+			ServiceName: DEFAULT_SERVICE_NAME,
+		},
+		Log: LogConfig{
+			Level:  DEFAULT_LOG_LEVEL,
+			Format: DEFAULT_LOG_FORMAT,
+		},
+		Cache: CacheConfig{
+			CatalogTTL:  DEFAULT_CATALOG_TTL,
+			UserInfoTTL: DEFAULT_USER_INFO_TTL,
+		},
+	}
+}
+
+// Load собирает конфигурацию из источников в порядке возрастания приоритета:
+// значения по умолчанию, YAML файл, переменные окружения (в том числе из env файла) и флаги командной строки.
+// Собранная конфигурация проверяется перед возвратом
+func Load(args []string) (*Config, error) {
+	config, _, err := LoadWithArgs(args)
+	return config, err
+}
+
+// LoadWithArgs собирает конфигурацию как Load и возвращает аргументы после флагов, например подкоманду утилиты
+func LoadWithArgs(args []string) (*Config, []string, error) {
+	flags := flag.NewFlagSet("merch", flag.ContinueOnError)
+	file := flags.String("config", os.Getenv("CONFIG_FILE"), "path to YAML config file")
+	envFile := flags.String("env-file", DEFAULT_ENV_FILE, "path to env file, ignored if it does not exist")
+
+	values := make(map[string]*string, len(params))
+	for _, p := range params {
+		values[p.flag] = flags.String(p.flag, "", fmt.Sprintf("%s (env %s)", p.usage, p.env))
+	}
+
+	err := flags.Parse(args)
+	if err != nil {
+		return nil, nil, configError(err.Error())
+	}
+
+	// Переменные из env файла не перезаписывают уже заданные в окружении
+	err = godotenv.Load(*envFile)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, nil, configError(fmt.Sprintf("Loading env file %s error: %v", *envFile, err))
+	}
+
+	config := Default()
+
+	if *file != "" {
+		err = config.loadFile(*file)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for _, p := range params {
+		value, ok := os.LookupEnv(p.env)
+		if !ok || value == "" {
+			continue
+		}
+
+		err = p.set(&config, value)
+		if err != nil {
+			return nil, nil, configError(fmt.Sprintf("Invalid %s: %v", p.env, err))
+		}
+	}
+
+	var flagErr error
+	flags.Visit(func(f *flag.Flag) {
+		for _, p := range params {
+			if p.flag == f.Name && flagErr == nil {
+				err := p.set(&config, *values[p.flag])
+				if err != nil {
+					flagErr = configError(fmt.Sprintf("Invalid -%s: %v", p.flag, err))
+				}
+			}
+		}
+	})
+	if flagErr != nil {
+		return nil, nil, flagErr
+	}
+
+	err = config.Validate()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &config, flags.Args(), nil
+}
+
+// loadFile читает YAML файл поверх текущих значений
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return configError(fmt.Sprintf("Reading config file error: %v", err))
+	}
+
+	err = yaml.Unmarshal(data, c)
+	if err != nil {
+		return configError(fmt.Sprintf("Parsing config file %s error: %v", path, err))
+	}
+
+	return nil
+}
+
+// Validate проверяет конфигурацию и возвращает все найденные ошибки
+func (c *Config) Validate() error {
+	var problems []error
+	invalid := func(format string, args ...any) {
+		problems = append(problems, fmt.Errorf(format, args...))
+	}
+
+	if c.Db.Host == "" {
+		invalid("DB_HOST is required")
+	}
+	if c.Db.User == "" {
+		invalid("DB_USER is required")
+	}
+	if c.Db.Name == "" {
+		invalid("DB_NAME is required")
+	}
+	if !validPort(c.Db.Port) {
+		invalid("DB_PORT must be a port number, got %q", c.Db.Port)
+	}
+	if c.Db.QueryTimeout <= 0 {
+		invalid("DB_QUERY_TIMEOUT must be positive")
+	}
+	if c.Db.MaxOpenConns < 0 || c.Db.MaxIdleConns < 0 {
+		invalid("DB_MAX_OPEN_CONNS and DB_MAX_IDLE_CONNS must not be negative")
+	}
+	if c.Db.MaxOpenConns > 0 && c.Db.MaxIdleConns > c.Db.MaxOpenConns {
+		invalid("DB_MAX_IDLE_CONNS must not exceed DB_MAX_OPEN_CONNS")
+	}
+	switch c.Db.SSLMode {
+	case domain.SSL_DISABLE, domain.SSL_REQUIRE, domain.SSL_VERIFY_CA, domain.SSL_VERIFY_FULL:
+	default:
+		invalid("DB_SSL_MODE must be one of disable, require, verify-ca, verify-full, got %q", c.Db.SSLMode)
+	}
+	if (c.Db.SSLCert == "") != (c.Db.SSLKey == "") {
+		invalid("DB_SSL_CERT and DB_SSL_KEY must be set together")
+	}
+	if c.Db.ConnectAttempts < 1 {
+		invalid("DB_CONNECT_ATTEMPTS must be at least 1")
+	}
+	if c.Db.StickyWindow < 0 {
+		invalid("DB_STICKY_WINDOW must not be negative")
+	}
+	for _, set := range c.Db.Seeds {
+		if !slices.Contains(domain.SEED_SETS, set) {
+			invalid("DB_SEEDS must contain only %s, got %q", strings.Join(domain.SEED_SETS, ", "), set)
+		}
+	}
+	if !validPort(c.Server.Port) {
+		invalid("SERVER_PORT must be a port number, got %q", c.Server.Port)
+	}
+	if c.Server.ShutdownTimeout <= 0 {
+		invalid("SHUTDOWN_TIMEOUT must be positive")
+	}
+	if len(c.Auth.SecretKey) < MIN_SECRET_KEY_LENGTH {
+		invalid("SECRET_KEY must be at least %d characters long", MIN_SECRET_KEY_LENGTH)
+	}
+	if c.OIDC.Issuer != "" {
+		if c.OIDC.Name == "" {
+			invalid("OIDC_NAME is required when OIDC_ISSUER is set")
+		}
+		if c.OIDC.ClientId == "" {
+			invalid("OIDC_CLIENT_ID is required when OIDC_ISSUER is set")
+		}
+		if c.OIDC.RedirectURL == "" {
+			invalid("OIDC_REDIRECT_URL is required when OIDC_ISSUER is set")
+		}
+	}
+
+	switch c.Tracing.Exporter {
+	case domain.TRACING_NONE, domain.TRACING_OTLP, domain.TRACING_STDOUT:
+	default:
+		invalid("TRACING_EXPORTER must be one of none, otlp, stdout, got %q", c.Tracing.Exporter)
+	}
+
+	switch c.Log.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		invalid("LOG_LEVEL must be one of debug, info, warn, error, got %q", c.Log.Level)
+	}
+	switch c.Log.Format {
+	case domain.LOG_FORMAT_JSON, domain.LOG_FORMAT_CONSOLE:
+	default:
+		invalid("LOG_FORMAT must be json or console, got %q", c.Log.Format)
+	}
+
+	if c.Cache.CatalogTTL < 0 || c.Cache.UserInfoTTL < 0 {
+		invalid("CACHE_CATALOG_TTL and CACHE_USER_INFO_TTL must not be negative")
+	}
+
+	if len(problems) != 0 {
+		return configError(fmt.Sprintf("Invalid configuration: %v", errors.Join(problems...)))
+	}
+
+	return nil
+}
+
+// Redacted возвращает копию конфигурации, в которой секреты заменены на REDACTED
+func (c Config) Redacted() Config {
+	redact := func(value *string) {
+		if *value != "" {
+			*value = REDACTED
+		}
+	}
+
+	redact(&c.Db.Password)
+	redact(&c.Auth.SecretKey)
+	redact(&c.OIDC.ClientSecret)
+	return c
+}
+
+// String возвращает конфигурацию без секретов, ее можно безопасно выводить в лог
+func (c Config) String() string {
+	// Отдельный тип без метода String, чтобы fmt не вызвал его рекурсивно
+	type plain Config
+	return fmt.Sprintf("%+v", plain(c.Redacted()))
+}
+
+func validPort(port string) bool {
+	number, err := strconv.Atoi(port)
+	return err == nil && number > 0 && number <= 65535
+}
+
+func configError(msg string) error {
+	return &ConfigError{
+		Kind: domain.ErrInternal,
+		Err:  msg,
+	}
+}
@@ -0,0 +1,143 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+// clearEnv очищает переменные конфигурации на время теста
+func clearEnv(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+	for _, p := range params {
+		t.Setenv(p.env, "")
+	}
+}
+
+// load вызывает Load без env файла
+func load(args ...string) (*Config, error) {
+	return Load(append([]string{"-env-file", filepath.Join(os.TempDir(), "merch-missing.env")}, args...))
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	clearEnv(t)
+
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(file, []byte(`
+db:
+  host: file-host
+  user: file-user
+  name: merch
+  query_timeout: 5s
+server:
+  port: "9000"
+auth:
+  secret_key: `+testSecret+`
+`), 0o600)
+	require.NoError(t, err)
+
+	t.Setenv("DB_HOST", "env-host")
+	t.Setenv("DB_USER", "env-user")
+
+	cfg, err := load("-config", file, "-db-user", "flag-user")
+	require.NoError(t, err)
+	assert.False(t, cfg.Db.SkipMigrate)
+	assert.False(t, cfg.Db.Options().NoStatementCache)
+	assert.Equal(t, DEFAULT_CATALOG_TTL, cfg.Cache.CatalogTTL)
+	assert.Equal(t, DEFAULT_USER_INFO_TTL, cfg.Cache.UserInfoTTL)
+	assert.Equal(t, []string{"catalog"}, cfg.Db.Seeds)
+
+	assert.Equal(t, "env-host", cfg.Db.Host)
+	assert.Equal(t, "flag-user", cfg.Db.User)
+	assert.Equal(t, "merch", cfg.Db.Name)
+	assert.Equal(t, 5*time.Second, cfg.Db.QueryTimeout)
+	assert.Equal(t, "9000", cfg.Server.Port)
+	assert.Equal(t, DEFAULT_DB_PORT, cfg.Db.Port)
+	assert.Equal(t, DEFAULT_SHUTDOWN_TIMEOUT, cfg.Server.ShutdownTimeout)
+	assert.Equal(t, testSecret, cfg.Auth.SecretKey)
+}
+
+func TestLoadWithArgs(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("DB_HOST", "localhost")
+	t.Setenv("DB_USER", "user")
+	t.Setenv("DB_NAME", "merch")
+	t.Setenv("SECRET_KEY", testSecret)
+
+	cfg, args, err := LoadWithArgs([]string{"-env-file", filepath.Join(os.TempDir(), "merch-missing.env"), "-db-skip-migrate=true", "-db-seeds", "catalog, demo", "-db-replica-hosts", "replica-1,replica-2:6432", "-db-statement-cache=false", "goto", "5"})
+	require.NoError(t, err)
+	assert.True(t, cfg.Db.SkipMigrate)
+	assert.Equal(t, []string{"catalog", "demo"}, cfg.Db.Seeds)
+	assert.Equal(t, []string{"replica-1", "replica-2:6432"}, cfg.Db.ReplicaHosts)
+	assert.Equal(t, DEFAULT_STICKY_WINDOW, cfg.Db.StickyWindow)
+	assert.True(t, cfg.Db.Options().NoStatementCache)
+	assert.Equal(t, []string{"goto", "5"}, args)
+
+	t.Setenv("DB_SKIP_MIGRATE", "maybe")
+	_, err = load()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Invalid DB_SKIP_MIGRATE")
+}
+
+func TestLoad_Validation(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want string
+	}{
+		{"weak secret", map[string]string{"SECRET_KEY": "short"}, "SECRET_KEY must be at least 32 characters long"},
+		{"missing host", map[string]string{"DB_HOST": ""}, "DB_HOST is required"},
+		{"bad port", map[string]string{"SERVER_PORT": "http"}, "SERVER_PORT must be a port number"},
+		{"bad duration", map[string]string{"DB_QUERY_TIMEOUT": "soon"}, "Invalid DB_QUERY_TIMEOUT"},
+		{"oidc without client", map[string]string{"OIDC_ISSUER": "https://idp.example.com"}, "OIDC_CLIENT_ID is required"},
+		{"unknown seed set", map[string]string{"DB_SEEDS": "catalog,prod"}, `DB_SEEDS must contain only catalog, demo, got "prod"`},
+		{"bad ssl mode", map[string]string{"DB_SSL_MODE": "prefer"}, "DB_SSL_MODE must be one of"},
+		{"cert without key", map[string]string{"DB_SSL_CERT": "/certs/client.pem"}, "DB_SSL_CERT and DB_SSL_KEY must be set together"},
+		{"idle above open", map[string]string{"DB_MAX_OPEN_CONNS": "5", "DB_MAX_IDLE_CONNS": "10"}, "DB_MAX_IDLE_CONNS must not exceed DB_MAX_OPEN_CONNS"},
+		{"bad pool size", map[string]string{"DB_MAX_OPEN_CONNS": "many"}, "Invalid DB_MAX_OPEN_CONNS"},
+		{"negative sticky window", map[string]string{"DB_STICKY_WINDOW": "-1s"}, "DB_STICKY_WINDOW must not be negative"},
+		{"negative cache ttl", map[string]string{"CACHE_USER_INFO_TTL": "-5s"}, "CACHE_CATALOG_TTL and CACHE_USER_INFO_TTL must not be negative"},
+		{"bad log format", map[string]string{"LOG_FORMAT": "xml"}, "LOG_FORMAT must be json or console"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			t.Setenv("DB_HOST", "localhost")
+			t.Setenv("DB_USER", "user")
+			t.Setenv("DB_NAME", "merch")
+			t.Setenv("SECRET_KEY", testSecret)
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			_, err := load()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
+
+func TestConfig_Redacted(t *testing.T) {
+	cfg := Default()
+	cfg.Db.Password = "db-password"
+	cfg.Auth.SecretKey = testSecret
+	cfg.OIDC.ClientSecret = "client-secret"
+
+	out := cfg.String()
+
+	for _, secret := range []string{"db-password", testSecret, "client-secret"} {
+		assert.False(t, strings.Contains(out, secret), "secret %q leaked", secret)
+	}
+	assert.Contains(t, out, REDACTED)
+
+	// Исходная конфигурация не меняется
+	assert.Equal(t, "db-password", cfg.Db.Password)
+}
@@ -0,0 +1,66 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// Права доступа API-ключей
+const (
+	SCOPE_COINS_GRANT  = "coins:grant"  // Начисление монет пользователям
+	SCOPE_BALANCE_READ = "balance:read" // Чтение балансов пользователей
+)
+
+// ApiKey - ключ для межсервисной авторизации
+type ApiKey struct {
+	Id         uint64     `json:"id"`         // Уникальный идентификатор ключа
+	Name       string     `json:"name"`       // Название интеграции, которой выдан ключ
+	Prefix     string     `json:"prefix"`     // Открытая часть ключа, по которой его можно узнать
+	Hash       string     `json:"-"`          // SHA-256 хэш ключа, сам ключ не хранится
+	Scopes     []string   `json:"scopes"`     // Права доступа
+	CreatedBy  uint64     `json:"createdBy"`  // Идентификатор администратора, выдавшего ключ
+	CreatedAt  time.Time  `json:"createdAt"`  // Дата выдачи
+	ExpiresAt  *time.Time `json:"expiresAt"`  // Дата окончания действия, nil - бессрочный
+	LastUsedAt *time.Time `json:"lastUsedAt"` // Дата последнего использования
+	RevokedAt  *time.Time `json:"revokedAt"`  // Дата отзыва
+}
+
+// HasScope проверяет наличие права доступа у ключа
+func (k *ApiKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Active проверяет, что ключ не отозван и не истек
+func (k *ApiKey) Active(now time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+
+	return k.ExpiresAt == nil || now.Before(*k.ExpiresAt)
+}
+
+// IsValidScope проверяет, существует ли право доступа
+func IsValidScope(scope string) bool {
+	switch scope {
+	case SCOPE_COINS_GRANT, SCOPE_BALANCE_READ:
+		return true
+	}
+
+	return false
+}
+
+// JoinScopes объединяет права доступа в строку для хранения
+func JoinScopes(scopes []string) string {
+	return strings.Join(scopes, " ")
+}
+
+// SplitScopes разбирает строку прав доступа
+func SplitScopes(scopes string) []string {
+	return strings.Fields(scopes)
+}
@@ -0,0 +1,39 @@
+package domain
+
+import "time"
+
+// Режимы TLS подключения к базе данных, как в sslmode libpq
+const (
+	SSL_DISABLE     = "disable"     // Без TLS
+	SSL_REQUIRE     = "require"     // TLS без проверки сертификата сервера
+	SSL_VERIFY_CA   = "verify-ca"   // TLS с проверкой, что сертификат сервера подписан SSLRootCert
+	SSL_VERIFY_FULL = "verify-full" // Как verify-ca, и имя сервера совпадает с сертификатом
+)
+
+// DbOptions - параметры пула соединений, TLS и подключения при запуске. Нулевые значения
+// оставляют настройки database/sql по умолчанию, пустой SSLMode означает SSL_DISABLE
+type DbOptions struct {
+	MaxOpenConns    int           // Максимум открытых соединений, 0 - без ограничения
+	MaxIdleConns    int           // Максимум простаивающих соединений
+	ConnMaxLifetime time.Duration // Через сколько соединение закрывается и открывается заново
+	ConnMaxIdleTime time.Duration // Через сколько простоя соединение закрывается
+
+	SSLMode     string // Режим TLS, одна из констант SSL_*
+	SSLRootCert string // Путь к сертификату центра, которым подписан сертификат сервера
+	SSLCert     string // Путь к клиентскому сертификату
+	SSLKey      string // Путь к ключу клиентского сертификата
+
+	NoStatementCache bool // Не кэшировать подготовленные запросы, например за PgBouncer в режиме transaction
+
+	ConnectAttempts int           // Сколько раз проверить подключение при создании, 0 - не проверять
+	ConnectBackoff  time.Duration // Пауза перед второй попыткой, затем удваивается до postgres.MAX_CONNECT_BACKOFF
+}
+
+// Наборы начальных данных из internal/presentation/seeds
+const (
+	SEED_CATALOG = "catalog" // Каталог товаров, нужен в каждом окружении
+	SEED_DEMO    = "demo"    // Демонстрационные пользователи с балансами для разработки и стендов
+)
+
+// SEED_SETS - все наборы начальных данных
+var SEED_SETS = []string{SEED_CATALOG, SEED_DEMO}
@@ -1,6 +1,9 @@
 package domain
 
 import (
+	"database/sql"
+	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -11,6 +14,14 @@ func TestUserCreate(t *testing.T) {
 	assert.Equal(t, "test@example.com", user.Email)
 	assert.Equal(t, "Password123", user.Password)
 	assert.Equal(t, 1000, user.Coins)
+	assert.Equal(t, ROLE_USER, user.Role)
+}
+
+func TestIsValidRole(t *testing.T) {
+	assert.True(t, IsValidRole(ROLE_USER))
+	assert.True(t, IsValidRole(ROLE_ADMIN))
+	assert.True(t, IsValidRole(ROLE_AUDITOR))
+	assert.False(t, IsValidRole("superuser"))
 }
 
 func TestTransactionCreate(t *testing.T) {
@@ -31,3 +42,38 @@ func TestInventoryCreate(t *testing.T) {
 	assert.Equal(t, "cup", inventory.Subject)
 	assert.Equal(t, uint64(1), inventory.UserId)
 }
+
+func TestBaseError(t *testing.T) {
+	err := fmt.Errorf("buying item: %w", &BaseError{
+		Kind:  ErrInvalidInput,
+		Err:   "Subject not exists",
+		Cause: sql.ErrNoRows,
+	})
+
+	assert.ErrorIs(t, err, ErrInvalidInput)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	assert.NotErrorIs(t, err, ErrNotFound)
+	assert.Equal(t, "buying item: Subject not exists: sql: no rows in result set", err.Error())
+
+	var baseErr *BaseError
+	assert.True(t, errors.As(err, &baseErr))
+	assert.Equal(t, ErrInvalidInput, baseErr.Kind)
+}
+
+func TestTypedError(t *testing.T) {
+	type first = TypedError[struct{ first bool }]
+	type second = TypedError[struct{ second bool }]
+
+	err := fmt.Errorf("wrapped: %w", &first{Kind: ErrNotFound, Err: "Not found", Cause: sql.ErrNoRows})
+	assert.Equal(t, "wrapped: Not found: sql: no rows in result set", err.Error())
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	var firstErr *first
+	var secondErr *second
+	var baseErr *BaseError
+	assert.True(t, errors.As(err, &firstErr))
+	assert.False(t, errors.As(err, &secondErr))
+	assert.True(t, errors.As(err, &baseErr))
+	assert.Equal(t, "Not found", baseErr.Err)
+}
@@ -0,0 +1,59 @@
+package domain
+
+import "errors"
+
+// Категории ошибок. Проверяются через errors.Is, текст каждой категории - стабильный код ошибки для клиентов API
+var (
+	ErrInvalidInput      = errors.New("invalid_input")      // Некорректные данные запроса
+	ErrUnauthorized      = errors.New("unauthorized")       // Нет или не подтверждена авторизация
+	ErrForbidden         = errors.New("forbidden")          // Недостаточно прав
+	ErrNotFound          = errors.New("not_found")          // Ресурс не найден
+	ErrInsufficientFunds = errors.New("insufficient_funds") // Недостаточно монет
+	ErrTooManyRequests   = errors.New("too_many_requests")  // Превышен лимит запросов или попыток
+	ErrInternal          = errors.New("internal")           // Внутренняя ошибка сервиса
+)
+
+// BaseError представляет собой ошибку приложения с категорией, сообщением, исходной причиной и подробностями
+type BaseError struct {
+	Kind    error          // Категория ошибки, одна из Err*
+	Err     string         // Сообщение об ошибке, показывается клиенту для всех категорий, кроме ErrInternal
+	Cause   error          // Исходная ошибка
+	Details map[string]any // Дополнительные сведения для клиента
+}
+
+// Error возвращает сообщение об ошибке вместе с исходной причиной
+func (e *BaseError) Error() string {
+	if e.Cause != nil {
+		return e.Err + ": " + e.Cause.Error()
+	}
+
+	return e.Err
+}
+
+// Unwrap возвращает категорию и исходную причину, чтобы обе находились через errors.Is и errors.As
+func (e *BaseError) Unwrap() []error {
+	errs := make([]error, 0, 2)
+	if e.Kind != nil {
+		errs = append(errs, e.Kind)
+	}
+	if e.Cause != nil {
+		errs = append(errs, e.Cause)
+	}
+
+	return errs
+}
+
+// TypedError - ошибка приложения отдельного типа с полями BaseError. Параметр T нужен только для того,
+// чтобы типы различались в errors.As: type DbQueryError = domain.TypedError[dbQueryError].
+// Unwrap возвращает ошибку как *BaseError, поэтому через нее находятся категория, причина и общие поля
+type TypedError[T any] BaseError
+
+// Error возвращает сообщение об ошибке вместе с исходной причиной
+func (e *TypedError[T]) Error() string {
+	return (*BaseError)(e).Error()
+}
+
+// Unwrap возвращает ошибку как *BaseError
+func (e *TypedError[T]) Unwrap() error {
+	return (*BaseError)(e)
+}
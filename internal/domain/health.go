@@ -0,0 +1,24 @@
+package domain
+
+// Статусы проверок состояния сервиса
+const (
+	HEALTH_OK   = "ok"
+	HEALTH_FAIL = "fail"
+)
+
+// HealthCheck - результат одной проверки состояния
+type HealthCheck struct {
+	Status string `json:"status"`          // HEALTH_OK или HEALTH_FAIL
+	Error  string `json:"error,omitempty"` // Причина неудачной проверки
+}
+
+// HealthReport - сводный результат проверок состояния сервиса
+type HealthReport struct {
+	Status string                 `json:"status"`           // HEALTH_OK, если все проверки прошли успешно
+	Checks map[string]HealthCheck `json:"checks,omitempty"` // Результаты проверок по имени
+}
+
+// Healthy сообщает, прошли ли все проверки
+func (r *HealthReport) Healthy() bool {
+	return r.Status == HEALTH_OK
+}
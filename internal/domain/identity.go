@@ -0,0 +1,16 @@
+package domain
+
+// ExternalIdentity - данные пользователя, подтвержденные внешним провайдером удостоверений
+type ExternalIdentity struct {
+	Provider      string `json:"provider"`      // Название провайдера
+	Subject       string `json:"subject"`       // Неизменяемый идентификатор пользователя у провайдера
+	Email         string `json:"email"`         // Email пользователя у провайдера
+	EmailVerified bool   `json:"emailVerified"` // Подтвердил ли провайдер email
+}
+
+// ExternalLogin - начало входа через внешнего провайдера
+type ExternalLogin struct {
+	URL   string `json:"url"`   // Адрес страницы входа провайдера
+	State string `json:"state"` // Значение для защиты от CSRF, возвращается провайдером в callback
+	Nonce string `json:"nonce"` // Значение для защиты от повторного использования ID-токена
+}
@@ -0,0 +1,10 @@
+package domain
+
+import "time"
+
+// LoginAttempt - состояние счетчика неудачных попыток входа для аккаунта или IP-адреса
+type LoginAttempt struct {
+	Key        string        `json:"key"`        // Ключ счетчика (email или IP-адрес)
+	Failures   int           `json:"failures"`   // Количество неудачных попыток подряд
+	RetryAfter time.Duration `json:"retryAfter"` // Оставшееся время блокировки
+}
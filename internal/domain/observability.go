@@ -0,0 +1,14 @@
+package domain
+
+// Форматы вывода логов
+const (
+	LOG_FORMAT_JSON    = "json"    // Одна JSON запись на строку, для сборщиков логов
+	LOG_FORMAT_CONSOLE = "console" // Читаемый формат для локальной разработки
+)
+
+// Экспортеры трассировки
+const (
+	TRACING_NONE   = "none"   // Трассировка выключена
+	TRACING_OTLP   = "otlp"   // Отправка спанов в коллектор OpenTelemetry по OTLP/HTTP
+	TRACING_STDOUT = "stdout" // Вывод спанов в stdout для отладки
+)
@@ -7,20 +7,41 @@ type AuthorizationData struct {
 	Id       uint64 `json:"id"`       // Уникальный идентификатор
 	Username string `json:"username"` // Имя пользователя
 	Password string `json:"password"` // Пароль пользователя
+	Role     string `json:"role"`     // Роль пользователя
 }
 
 // AuthorizationToken представляет токен авторизации пользователя
 type AuthorizationToken struct {
 	Id      uint64    `json:"id"`      // Уникальный идентификатор токена
 	Email   string    `json:"email"`   // Email пользователя, связанный с токеном
+	Role    string    `json:"role"`    // Роль пользователя на момент выдачи токена
 	Expires time.Time `json:"expires"` // Дата окончания действия токена
 }
 
-// UserInfo содержит информацию о пользователе, включая монеты, инвентарь и транзакции
+// LoginResult - результат входа: токен доступа или, если включена двухфакторная аутентификация, токен подтверждения
+type LoginResult struct {
+	Token     Token `json:"token,omitempty"`     // JWT для доступа к API
+	Challenge Token `json:"challenge,omitempty"` // Короткоживущий токен для второго шага входа
+}
+
+// UserInfo содержит информацию о пользователе: монеты, инвентарь по видам предметов и историю переводов
 type UserInfo struct {
-	Coins        int           `json:"coins"`       // Количество монет
-	Inventory    []Inventory   `json:"inventory"`   // Инвентарь пользователя
-	Transactions []Transaction `json:"coinHistory"` // История транзакций пользователя
+	Coins     int            `json:"coins"`     // Количество монет
+	Inventory []ItemQuantity `json:"inventory"` // Купленные предметы с количеством
+	Received  []CoinTransfer `json:"received"`  // Полученные переводы, от старых к новым
+	Sent      []CoinTransfer `json:"sent"`      // Отправленные переводы, от старых к новым
+}
+
+// ItemQuantity - сколько предметов одного вида есть у пользователя
+type ItemQuantity struct {
+	Type     string `json:"type"`     // Название предмета
+	Quantity int    `json:"quantity"` // Количество
+}
+
+// CoinTransfer - перевод в истории пользователя
+type CoinTransfer struct {
+	User   UserEmail `json:"user"`   // Второй участник перевода: отправитель для полученных, получатель для отправленных
+	Amount Amount    `json:"amount"` // Сумма перевода
 }
 
 // Алиасы типов для удобства использования
@@ -32,22 +53,18 @@ type UserEmail = string
 
 type Msg = string
 
-type Amount = int
-
-type SuccessfulAuth = bool
-
-// BaseError представляет собой базовую ошибку с кодом и сообщением
-type BaseError struct {
-	Code int    // Код ошибки
-	Err  string // Сообщение об ошибке
+// LogField - поле структурированной записи лога
+type LogField struct {
+	Key   string // Имя поля
+	Value any    // Значение поля
 }
 
-// Error возвращает сообщение об ошибке
-func (e *BaseError) Error() string {
-	return e.Err
+// Field создает поле структурированной записи лога
+func Field(key string, value any) LogField {
+	return LogField{Key: key, Value: value}
 }
 
-// GetCode возвращает код ошибки
-func (e *BaseError) GetCode() int {
-	return e.Code
-}
+type Amount = int
+
+type SuccessfulAuth = bool
+type Role = string
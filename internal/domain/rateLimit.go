@@ -0,0 +1,24 @@
+package domain
+
+import "time"
+
+// RateLimit - политика ограничения частоты запросов по алгоритму token bucket
+type RateLimit struct {
+	Requests int           `json:"requests"` // Количество запросов, восполняемых за период
+	Period   time.Duration `json:"period"`   // Период восполнения
+	Burst    int           `json:"burst"`    // Емкость корзины - максимальное количество запросов подряд
+}
+
+// Enabled сообщает, задано ли ограничение
+func (l RateLimit) Enabled() bool {
+	return l.Requests > 0 && l.Period > 0 && l.Burst > 0
+}
+
+// RateLimitResult - результат проверки ограничения частоты запросов
+type RateLimitResult struct {
+	Allowed    bool          `json:"allowed"`    // Разрешен ли запрос
+	Limit      int           `json:"limit"`      // Емкость корзины
+	Remaining  int           `json:"remaining"`  // Оставшееся количество запросов
+	Reset      time.Duration `json:"reset"`      // Время до полного восполнения корзины
+	RetryAfter time.Duration `json:"retryAfter"` // Время до появления следующего токена, если запрос отклонен
+}
@@ -16,3 +16,11 @@ func CreateTransaction(senderName, receiverName string, amount int) *Transaction
 		Amount:       amount,
 	}
 }
+
+// Grant - начисление монет пользователю от имени интеграции
+type Grant struct {
+	Id           uint64    `json:"id"`           // Уникальный идентификатор начисления
+	ApiKeyId     uint64    `json:"apiKeyId"`     // Идентификатор API-ключа, которым выполнено начисление
+	ReceiverName UserEmail `json:"receiverName"` // Email получателя
+	Amount       Amount    `json:"amount"`       // Сумма начисления
+}
@@ -0,0 +1,15 @@
+package domain
+
+// TwoFactor - настройки двухфакторной аутентификации пользователя
+type TwoFactor struct {
+	UserId   uint64 `json:"userId"`   // Идентификатор пользователя
+	Secret   string `json:"-"`        // Секрет TOTP в кодировке base32
+	Enabled  bool   `json:"enabled"`  // Подтверждено ли подключение
+	LastStep int64  `json:"lastStep"` // Номер последнего использованного временного шага, защищает от повторного использования кода
+}
+
+// TwoFactorEnrollment - данные для подключения приложения-аутентификатора
+type TwoFactorEnrollment struct {
+	Secret string `json:"secret"` // Секрет для ручного ввода
+	URI    string `json:"uri"`    // Ссылка otpauth:// для QR-кода
+}
@@ -1,18 +1,37 @@
 package domain
 
+// Роли пользователей
+const (
+	ROLE_USER    = "user"    // Обычный пользователь
+	ROLE_ADMIN   = "admin"   // Администратор с полным доступом к /api/admin
+	ROLE_AUDITOR = "auditor" // Аудитор с доступом к /api/admin только на чтение
+)
+
 // User - объект пользователя системы
 type User struct {
 	Id       uint64 `json:"id"`       // Уникальный идентификатор пользователя
 	Email    string `json:"email"`    // Email пользователя
 	Password string `json:"password"` // Пароль пользователя (хранится только в зашифрованном виде)
 	Coins    int    `json:"coins"`    // Количество монет у пользователя
+	Role     string `json:"role"`     // Роль пользователя
 }
 
-// CreateUser создает нового пользователя
+// CreateUser создает нового пользователя с ролью ROLE_USER
 func CreateUser(email, pass string, coins int) *User {
 	return &User{
 		Email:    email,
 		Password: pass,
 		Coins:    coins,
+		Role:     ROLE_USER,
 	}
 }
+
+// IsValidRole проверяет, существует ли роль
+func IsValidRole(role Role) bool {
+	switch role {
+	case ROLE_USER, ROLE_ADMIN, ROLE_AUDITOR:
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,24 @@
+package interfaces
+
+import (
+	"context"
+	"merch/internal/domain"
+)
+
+// ApiKeyRepo предоставляет методы для работы с API-ключами
+type ApiKeyRepo interface {
+	// Create сохраняет новый ключ и возвращает его идентификатор
+	Create(ctx context.Context, key domain.ApiKey) (id *uint64, err error)
+
+	// GetByHash получает ключ по хэшу или nil, если такого ключа нет
+	GetByHash(ctx context.Context, hash string) (key *domain.ApiKey, err error)
+
+	// List возвращает все выданные ключи
+	List(ctx context.Context) (keys []domain.ApiKey, err error)
+
+	// Touch обновляет дату последнего использования ключа
+	Touch(ctx context.Context, id uint64) error
+
+	// Revoke отзывает ключ
+	Revoke(ctx context.Context, id uint64) error
+}
@@ -1,15 +1,39 @@
 package interfaces
 
-import "merch/internal/domain"
+import (
+	"context"
+	"merch/internal/domain"
+)
 
 // AuthRepo предоставляет методы для работы с авторизационными токенами и доступом
 type AuthRepo interface {
 	// CreateToken создает авторизационный токен
-	CreateToken(data domain.AuthorizationData) (token *domain.Token, err error)
+	CreateToken(ctx context.Context, data domain.AuthorizationData) (token *domain.Token, err error)
 
 	// DecodeToken декодирует авторизационный токен
 	DecodeToken(token domain.Token) (data *domain.AuthorizationToken, err error)
 
 	// Access проверяет не был ли токен отозван
-	Access(token domain.Token, userId domain.UserId) (exists *domain.SuccessfulAuth, err error)
+	Access(ctx context.Context, token domain.Token, userId domain.UserId) (exists *domain.SuccessfulAuth, err error)
+
+	// CreateChallenge создает короткоживущий токен подтверждения для второго шага входа
+	CreateChallenge(data domain.AuthorizationData) (token *domain.Token, err error)
+
+	// DecodeChallenge декодирует токен подтверждения
+	DecodeChallenge(token domain.Token) (data *domain.AuthorizationToken, err error)
+
+	// Revoke отзывает все токены пользователя
+	Revoke(ctx context.Context, userId domain.UserId) error
+}
+
+// IdentityProvider предоставляет методы для входа через внешний провайдер удостоверений
+type IdentityProvider interface {
+	// Name возвращает название провайдера, под которым он доступен в API
+	Name() string
+
+	// AuthCodeURL возвращает адрес страницы входа провайдера
+	AuthCodeURL(state, nonce string) string
+
+	// Exchange обменивает код авторизации на подтвержденные данные пользователя
+	Exchange(ctx context.Context, code, nonce string) (identity *domain.ExternalIdentity, err error)
 }
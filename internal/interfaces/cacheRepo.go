@@ -0,0 +1,18 @@
+package interfaces
+
+import (
+	"context"
+	"time"
+)
+
+// CacheRepo предоставляет хранилище закэшированных значений с временем жизни, например в памяти процесса или в Redis
+type CacheRepo interface {
+	// Get возвращает значение по ключу и сообщает, найдено ли оно
+	Get(ctx context.Context, key string) (value []byte, found bool, err error)
+
+	// Set сохраняет значение по ключу на время ttl
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+
+	// Delete удаляет значения по ключам, отсутствующие ключи пропускаются
+	Delete(ctx context.Context, keys ...string) error
+}
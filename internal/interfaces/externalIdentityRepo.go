@@ -0,0 +1,15 @@
+package interfaces
+
+import (
+	"context"
+	"merch/internal/domain"
+)
+
+// ExternalIdentityRepo предоставляет методы для связи внешних учетных записей с пользователями
+type ExternalIdentityRepo interface {
+	// GetUserId получает идентификатор пользователя, связанного с внешней учетной записью
+	GetUserId(ctx context.Context, provider, subject string) (id *domain.UserId, err error)
+
+	// Link связывает внешнюю учетную запись с пользователем
+	Link(ctx context.Context, provider, subject string, userId domain.UserId) error
+}
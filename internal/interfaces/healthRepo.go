@@ -0,0 +1,12 @@
+package interfaces
+
+import "context"
+
+// HealthRepo предоставляет методы для проверки готовности хранилища
+type HealthRepo interface {
+	// Ping проверяет доступность базы данных
+	Ping(ctx context.Context) error
+
+	// SchemaVersion возвращает версию примененных миграций и признак незавершенной миграции
+	SchemaVersion(ctx context.Context) (version uint, dirty bool, err error)
+}
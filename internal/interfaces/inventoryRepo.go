@@ -1,15 +1,18 @@
 package interfaces
 
-import "merch/internal/domain"
+import (
+	"context"
+	"merch/internal/domain"
+)
 
 // InventoryRepo предоставляет методы для работы с инвентарем
 type InventoryRepo interface {
 	// Buy выполняет покупку предмета пользователем
-	Buy(userId domain.UserId, subject domain.Item) error
+	Buy(ctx context.Context, userId domain.UserId, subject domain.Item) error
 
 	// GetSubjectByName получает предмет по его названию
-	GetSubjectByName(email domain.UserEmail) (subject *domain.Item, err error)
+	GetSubjectByName(ctx context.Context, email domain.UserEmail) (subject *domain.Item, err error)
 
 	// GetInventory получает инвентарь пользователя по его идентификатору
-	GetInventory(userId domain.UserId) (inventory *[]domain.Inventory, err error)
+	GetInventory(ctx context.Context, userId domain.UserId) (inventory *[]domain.Inventory, err error)
 }
@@ -4,9 +4,12 @@ import "merch/internal/domain"
 
 // Интерфейс LoggerRepo определяет методы для логирования сообщений
 type LoggerRepo interface {
-	Fatal(msg domain.Msg)
-	Error(msg domain.Msg)
-	Warn(msg domain.Msg)
-	Info(msg domain.Msg)
-	Debug(msg domain.Msg)
+	Fatal(msg domain.Msg, fields ...domain.LogField)
+	Error(msg domain.Msg, fields ...domain.LogField)
+	Warn(msg domain.Msg, fields ...domain.LogField)
+	Info(msg domain.Msg, fields ...domain.LogField)
+	Debug(msg domain.Msg, fields ...domain.LogField)
+
+	// With возвращает дочерний логгер, который добавляет fields к каждой записи
+	With(fields ...domain.LogField) LoggerRepo
 }
@@ -0,0 +1,22 @@
+package interfaces
+
+import (
+	"context"
+	"merch/internal/domain"
+	"time"
+)
+
+// LoginAttemptRepo предоставляет методы для учета неудачных попыток входа
+type LoginAttemptRepo interface {
+	// Get возвращает состояние счетчика по ключу или nil, если неудачных попыток не было
+	Get(ctx context.Context, key string) (attempt *domain.LoginAttempt, err error)
+
+	// Fail увеличивает счетчик неудачных попыток и возвращает его новое значение
+	Fail(ctx context.Context, key string, window time.Duration) (failures int, err error)
+
+	// Lock блокирует вход по ключу на указанное время
+	Lock(ctx context.Context, key string, duration time.Duration) error
+
+	// Reset сбрасывает счетчик неудачных попыток
+	Reset(ctx context.Context, key string) error
+}
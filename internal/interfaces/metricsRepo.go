@@ -0,0 +1,30 @@
+package interfaces
+
+import (
+	"net/http"
+	"time"
+)
+
+// MetricsRepo предоставляет методы для сбора метрик сервиса
+type MetricsRepo interface {
+	// ObserveRequest учитывает обработанный http запрос
+	ObserveRequest(method, route string, status int, duration time.Duration)
+
+	// CoinsTransferred учитывает монеты, переведенные между пользователями
+	CoinsTransferred(amount int)
+
+	// ItemPurchased учитывает покупку товара
+	ItemPurchased(item string)
+
+	// LoginFailed учитывает неудачную попытку входа с указанием причины
+	LoginFailed(reason string)
+
+	// CacheHit учитывает значение, найденное в кэше с указанным именем
+	CacheHit(cache string)
+
+	// CacheMiss учитывает значение, которого не было в кэше и которое загружено из базы данных
+	CacheMiss(cache string)
+
+	// Handler возвращает http обработчик, отдающий метрики
+	Handler() http.Handler
+}
@@ -0,0 +1,15 @@
+package interfaces
+
+import "time"
+
+// OTPRepo предоставляет методы для работы с одноразовыми паролями
+type OTPRepo interface {
+	// GenerateSecret создает новый секрет
+	GenerateSecret() (secret string, err error)
+
+	// URI возвращает ссылку otpauth:// для подключения приложения-аутентификатора
+	URI(secret, account string) string
+
+	// Verify проверяет код на момент времени at и возвращает номер временного шага, которому он соответствует
+	Verify(secret, code string, at time.Time) (step int64, ok bool)
+}
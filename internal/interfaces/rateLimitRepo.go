@@ -0,0 +1,12 @@
+package interfaces
+
+import (
+	"context"
+	"merch/internal/domain"
+)
+
+// RateLimitRepo предоставляет хранилище корзин для ограничения частоты запросов
+type RateLimitRepo interface {
+	// Allow списывает токен из корзины по ключу и сообщает, разрешен ли запрос
+	Allow(ctx context.Context, key string, limit domain.RateLimit) (result *domain.RateLimitResult, err error)
+}
@@ -0,0 +1,27 @@
+package interfaces
+
+import (
+	"context"
+	"merch/internal/domain"
+)
+
+// TwoFactorRepo предоставляет методы для хранения настроек двухфакторной аутентификации
+type TwoFactorRepo interface {
+	// Get получает настройки пользователя или nil, если двухфакторная аутентификация не подключалась
+	Get(ctx context.Context, userId domain.UserId) (twoFactor *domain.TwoFactor, err error)
+
+	// SetSecret сохраняет новый неподтвержденный секрет
+	SetSecret(ctx context.Context, userId domain.UserId, secret string) error
+
+	// Enable подтверждает подключение и заменяет резервные коды их хэшами
+	Enable(ctx context.Context, userId domain.UserId, recoveryHashes []string) error
+
+	// Disable отключает двухфакторную аутентификацию и удаляет резервные коды
+	Disable(ctx context.Context, userId domain.UserId) error
+
+	// UseStep отмечает временной шаг использованным. Возвращает false, если этот или более поздний шаг уже использовался
+	UseStep(ctx context.Context, userId domain.UserId, step int64) (ok bool, err error)
+
+	// UseRecoveryCode погашает резервный код по хэшу. Возвращает false, если код не найден или уже использован
+	UseRecoveryCode(ctx context.Context, userId domain.UserId, hash string) (ok bool, err error)
+}
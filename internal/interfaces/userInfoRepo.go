@@ -0,0 +1,12 @@
+package interfaces
+
+import (
+	"context"
+	"merch/internal/domain"
+)
+
+// UserInfoRepo предоставляет сводную информацию о пользователе для /api/info
+type UserInfoRepo interface {
+	// GetInfo получает баланс, инвентарь и историю переводов пользователя по его идентификатору
+	GetInfo(ctx context.Context, userId domain.UserId) (info *domain.UserInfo, err error)
+}
@@ -1,15 +1,24 @@
 package interfaces
 
-import "merch/internal/domain"
+import (
+	"context"
+	"merch/internal/domain"
+)
 
 // UserRepo предоставляет методы для работы с пользователями
 type UserRepo interface {
 	// Create создает нового пользователя и возвращает его идентификатор
-	Create(user domain.User) (id *domain.UserId, err error)
+	Create(ctx context.Context, user domain.User) (id *domain.UserId, err error)
 
 	// GetById получает пользователя по его идентификатору
-	GetById(id domain.UserId) (user *domain.User, err error)
+	GetById(ctx context.Context, id domain.UserId) (user *domain.User, err error)
 
 	// GetByEmail получает пользователя по его email
-	GetByEmail(email domain.UserEmail) (user *domain.User, err error)
+	GetByEmail(ctx context.Context, email domain.UserEmail) (user *domain.User, err error)
+
+	// SetRole изменяет роль пользователя с указанным email
+	SetRole(ctx context.Context, email domain.UserEmail, role domain.Role) error
+
+	// AddCoins начисляет монеты получателю и сохраняет запись о начислении
+	AddCoins(ctx context.Context, grant domain.Grant) error
 }
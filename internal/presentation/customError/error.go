@@ -2,25 +2,89 @@ package customError
 
 import "merch/internal/domain"
 
-// Алиасы типов для различных типов ошибок
-type JWTGenerationError = domain.BaseError
+// Типы ошибок приложения. Каждый тип - domain.TypedError со своей меткой: категория и причина
+// находятся через errors.Is, конкретный тип - через errors.As, а общие поля - через errors.As с *domain.BaseError
 
-type JWTDecodeError = domain.BaseError
+// JWTGenerationError - ошибка создания JWT токена
+type JWTGenerationError = domain.TypedError[jwtGenerationError]
 
-type NeedAuthorization = domain.BaseError
+// JWTDecodeError - ошибка разбора или проверки JWT токена
+type JWTDecodeError = domain.TypedError[jwtDecodeError]
 
-type InvalidPasswordFormat = domain.BaseError
+// NeedAuthorization - ошибка отсутствующей или недействительной авторизации
+type NeedAuthorization = domain.TypedError[needAuthorizationError]
 
-type MigratingError = domain.BaseError
+// InvalidPasswordFormat - ошибка пароля, не подходящего под требования
+type InvalidPasswordFormat = domain.TypedError[invalidPasswordFormatError]
 
-type DbConnectionError = domain.BaseError
+// InvalidRequest - ошибка некорректного запроса
+type InvalidRequest = domain.TypedError[invalidRequestError]
 
-type DbQueryError = domain.BaseError
+// MigratingError - ошибка применения миграций
+type MigratingError = domain.TypedError[migratingError]
 
-type UserCreatingError = domain.BaseError
+// DbConnectionError - ошибка подключения к базе данных
+type DbConnectionError = domain.TypedError[dbConnectionError]
 
-type RowsNotFoundError = domain.BaseError
+// DbQueryError - ошибка запроса к базе данных
+type DbQueryError = domain.TypedError[dbQueryError]
 
-type TransactionError = domain.BaseError
+// UserCreatingError - ошибка создания пользователя
+type UserCreatingError = domain.TypedError[userCreatingError]
 
-type LoggerBuildError = domain.BaseError
+// RowsNotFoundError - ошибка отсутствия записи в базе данных
+type RowsNotFoundError = domain.TypedError[rowsNotFoundError]
+
+// TransactionError - ошибка транзакции базы данных
+type TransactionError = domain.TypedError[transactionError]
+
+// LoggerBuildError - ошибка создания логгера
+type LoggerBuildError = domain.TypedError[loggerBuildError]
+
+// TooManyAttempts - ошибка превышения числа попыток входа
+type TooManyAttempts = domain.TypedError[tooManyAttemptsError]
+
+// RateLimitExceeded - ошибка превышения лимита запросов
+type RateLimitExceeded = domain.TypedError[rateLimitExceededError]
+
+// AccessDenied - ошибка недостатка прав
+type AccessDenied = domain.TypedError[accessDeniedError]
+
+// OTPError - ошибка одноразового кода
+type OTPError = domain.TypedError[otpError]
+
+// IdentityProviderError - ошибка провайдера удостоверений
+type IdentityProviderError = domain.TypedError[identityProviderError]
+
+// TracingError - ошибка настройки трассировки
+type TracingError = domain.TypedError[tracingError]
+
+// PanicError - паника в обработчике запроса
+type PanicError = domain.TypedError[panicError]
+
+// SeedError - ошибка применения начальных данных
+type SeedError = domain.TypedError[seedError]
+
+// Метки, которые делают типы ошибок различными
+type (
+	jwtGenerationError         struct{}
+	jwtDecodeError             struct{}
+	needAuthorizationError     struct{}
+	invalidPasswordFormatError struct{}
+	invalidRequestError        struct{}
+	migratingError             struct{}
+	dbConnectionError          struct{}
+	dbQueryError               struct{}
+	userCreatingError          struct{}
+	rowsNotFoundError          struct{}
+	transactionError           struct{}
+	loggerBuildError           struct{}
+	tooManyAttemptsError       struct{}
+	rateLimitExceededError     struct{}
+	accessDeniedError          struct{}
+	otpError                   struct{}
+	identityProviderError      struct{}
+	tracingError               struct{}
+	panicError                 struct{}
+	seedError                  struct{}
+)
@@ -0,0 +1,8 @@
+BEGIN;
+
+-- Исходные токены по хэшам не восстановить, поэтому все сессии сбрасываются
+DELETE FROM Token;
+
+ALTER TABLE Token ALTER COLUMN value TYPE VARCHAR(256);
+
+COMMIT;
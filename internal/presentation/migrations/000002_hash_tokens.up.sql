@@ -0,0 +1,9 @@
+BEGIN;
+
+-- Замена сохраненных JWT на их SHA-256 хэши
+UPDATE Token SET value = encode(sha256(value::bytea), 'hex');
+
+-- Хэш в шестнадцатеричном виде всегда занимает 64 символа
+ALTER TABLE Token ALTER COLUMN value TYPE CHAR(64);
+
+COMMIT;
@@ -0,0 +1,11 @@
+BEGIN;
+
+-- Создание таблицы для учета неудачных попыток входа по аккаунтам и IP-адресам
+CREATE TABLE LoginAttempt (
+    login_key    VARCHAR(320) PRIMARY KEY,
+    failures     INT NOT NULL,
+    last_failure TIMESTAMP NOT NULL,
+    locked_until TIMESTAMP
+);
+
+COMMIT;
@@ -0,0 +1,5 @@
+BEGIN;
+
+ALTER TABLE Users DROP COLUMN IF EXISTS role;
+
+COMMIT;
@@ -0,0 +1,7 @@
+BEGIN;
+
+-- Добавление роли пользователя, все существующие пользователи получают роль user
+ALTER TABLE Users ADD COLUMN role VARCHAR(16) NOT NULL DEFAULT 'user'
+    CHECK (role IN ('user', 'admin', 'auditor'));
+
+COMMIT;
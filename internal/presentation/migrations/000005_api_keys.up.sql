@@ -0,0 +1,18 @@
+BEGIN;
+
+-- Создание таблицы API-ключей для межсервисных интеграций
+CREATE TABLE ApiKey (
+    id           SERIAL PRIMARY KEY,
+    name         VARCHAR(128) NOT NULL,
+    prefix       VARCHAR(16) NOT NULL,
+    hash         CHAR(64) NOT NULL UNIQUE,
+    scopes       VARCHAR(256) NOT NULL,
+    created_by   INT NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at   TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ,
+    revoked_at   TIMESTAMPTZ,
+    FOREIGN KEY (created_by) REFERENCES Users(id)
+);
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE IF EXISTS RecoveryCode;
+DROP TABLE IF EXISTS TwoFactor;
+
+COMMIT;
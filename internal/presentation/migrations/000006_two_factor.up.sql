@@ -0,0 +1,24 @@
+BEGIN;
+
+-- Создание таблицы настроек двухфакторной аутентификации
+CREATE TABLE TwoFactor (
+    user_id   INT PRIMARY KEY,
+    secret    VARCHAR(64) NOT NULL,
+    enabled   BOOLEAN NOT NULL DEFAULT FALSE,
+    last_step BIGINT NOT NULL DEFAULT 0,
+    FOREIGN KEY (user_id) REFERENCES Users(id)
+);
+
+-- Создание таблицы резервных кодов
+CREATE TABLE RecoveryCode (
+    id      SERIAL PRIMARY KEY,
+    user_id INT NOT NULL,
+    hash    CHAR(64) NOT NULL,
+    used_at TIMESTAMPTZ,
+    FOREIGN KEY (user_id) REFERENCES Users(id)
+);
+
+-- Создание индекса на user_id в таблице RecoveryCode
+CREATE INDEX recovery_code_user_id_idx ON RecoveryCode(user_id);
+
+COMMIT;
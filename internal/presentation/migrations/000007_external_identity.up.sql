@@ -0,0 +1,16 @@
+BEGIN;
+
+-- Создание таблицы внешних учетных записей (SSO)
+CREATE TABLE ExternalIdentity (
+    provider   VARCHAR(64) NOT NULL,
+    subject    VARCHAR(255) NOT NULL,
+    user_id    INT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (provider, subject),
+    FOREIGN KEY (user_id) REFERENCES Users(id)
+);
+
+-- Создание индекса на user_id в таблице ExternalIdentity
+CREATE INDEX external_identity_user_id_idx ON ExternalIdentity(user_id);
+
+COMMIT;
@@ -0,0 +1,17 @@
+BEGIN;
+
+-- Создание таблицы начислений монет интеграциями
+CREATE TABLE CoinGrant (
+    id            SERIAL PRIMARY KEY,
+    api_key_id    INT NOT NULL,
+    receiver_name VARCHAR(256) NOT NULL,
+    amount        INT NOT NULL CHECK (amount > 0),
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    FOREIGN KEY (api_key_id) REFERENCES ApiKey(id),
+    FOREIGN KEY (receiver_name) REFERENCES Users(email)
+);
+
+-- Создание индекса на receiver_name в таблице CoinGrant
+CREATE INDEX coin_grant_receiver_name_idx ON CoinGrant(receiver_name);
+
+COMMIT;
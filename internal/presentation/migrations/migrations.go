@@ -0,0 +1,9 @@
+package migrations
+
+import "embed"
+
+// FS - файлы миграций, встроенные в бинарный файл, чтобы миграции не зависели от рабочей директории.
+// Имена файлов - {версия}_{название}.up.sql и {версия}_{название}.down.sql в формате golang-migrate
+//
+//go:embed *.sql
+var FS embed.FS
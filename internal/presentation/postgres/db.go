@@ -1,47 +1,166 @@
 package postgres
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"fmt"
+	"merch/internal/domain"
 	"merch/internal/interfaces"
 	e "merch/internal/presentation/customError"
-	"net/http"
-)
+	"net"
+	"strings"
+	"time"
 
-var (
-	DbService *DB
+	"github.com/XSAM/otelsql"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// PING_TIMEOUT - время ожидания ответа базы данных на одну проверку подключения
+const PING_TIMEOUT = 5 * time.Second
+
+// MAX_CONNECT_BACKOFF - максимальная пауза между попытками подключения
+const MAX_CONNECT_BACKOFF = 30 * time.Second
+
 // DB - структура для работы с базой данных
 type DB struct {
-	Db     *sql.DB
-	Logger interfaces.LoggerRepo
+	Db        *sql.DB
+	Logger    interfaces.LoggerRepo
+	addr      string           // Адрес сервера host:port
+	read      *replicas        // Реплики для чтения, nil - все запросы выполняются на основном сервере
+	connector driver.Connector // Подключение pgx, из которого открывается отдельный пул для миграций
 }
 
-// CreateDB создает подключение к базе данных и возвращает экземпляр DB
-func CreateDB(ip, port, user, pass, nameDB string, logger interfaces.LoggerRepo) (*DB, error) {
+// CreateDB создает пул подключений к базе данных с параметрами opts и возвращает экземпляр DB.
+// Если opts.ConnectAttempts больше нуля, дожидается доступности базы данных, повторяя попытки с растущей паузой.
+// Если передан tracer, каждый запрос к базе данных записывается отдельным спаном
+func CreateDB(ip, port, user, pass, nameDB string, opts domain.DbOptions, logger interfaces.LoggerRepo, tracer trace.TracerProvider) (*DB, error) {
 	logger.Debug("Database connection creating...")
-	sqlInfo := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable", ip, port, user, pass, nameDB)
-	conn, err := sql.Open("postgres", sqlInfo)
+	sqlInfo := dsn(ip, port, user, pass, nameDB, opts)
 
+	connConfig, err := pgx.ParseConfig(sqlInfo)
 	if err != nil {
 		return nil, &e.DbConnectionError{
-			Code: http.StatusInternalServerError,
-			Err:  fmt.Sprintf("Database connection error: %v", err),
+			Kind:  domain.ErrInternal,
+			Err:   "Database connection error",
+			Cause: err,
 		}
 	}
 
-	DbService = &DB{
-		Db:     conn,
-		Logger: logger,
+	// pgx подготавливает запрос при первом выполнении на соединении и дальше выполняет его без разбора
+	connConfig.DefaultQueryExecMode = pgx.QueryExecModeCacheStatement
+	if opts.NoStatementCache {
+		connConfig.DefaultQueryExecMode = pgx.QueryExecModeExec
+	}
+	connector := stdlib.GetConnector(*connConfig)
+
+	var conn *sql.DB
+	if tracer != nil {
+		conn = otelsql.OpenDB(connector,
+			otelsql.WithTracerProvider(tracer),
+			otelsql.WithAttributes(attribute.String("db.system", "postgresql"), attribute.String("db.name", nameDB)),
+			otelsql.WithSpanOptions(otelsql.SpanOptions{OmitConnResetSession: true, OmitRows: true}),
+		)
+	} else {
+		conn = sql.OpenDB(connector)
+	}
+
+	conn.SetMaxOpenConns(opts.MaxOpenConns)
+	conn.SetMaxIdleConns(opts.MaxIdleConns)
+	conn.SetConnMaxLifetime(opts.ConnMaxLifetime)
+	conn.SetConnMaxIdleTime(opts.ConnMaxIdleTime)
+
+	db := &DB{
+		Db:        conn,
+		Logger:    logger,
+		addr:      net.JoinHostPort(ip, port),
+		connector: connector,
+	}
+
+	err = db.connect(context.Background(), opts.ConnectAttempts, opts.ConnectBackoff)
+	if err != nil {
+		_ = conn.Close()
+		return nil, &e.DbConnectionError{
+			Kind:  domain.ErrInternal,
+			Err:   "Database is not available",
+			Cause: err,
+		}
 	}
 
 	logger.Info("Database connection has been created")
-	return DbService, nil
+	return db, nil
+}
+
+// connect проверяет подключение до attempts раз, удваивая паузу между попытками
+func (db *DB) connect(ctx context.Context, attempts int, backoff time.Duration) error {
+	for attempt := 1; attempt <= attempts; attempt++ {
+		pingCtx, cancel := context.WithTimeout(ctx, PING_TIMEOUT)
+		err := db.Db.PingContext(pingCtx)
+		cancel()
+
+		if err == nil {
+			return nil
+		}
+		if attempt == attempts {
+			return err
+		}
+
+		db.Logger.Warn("Database is not available, retrying",
+			domain.Field("attempt", attempt),
+			domain.Field("retryIn", backoff.String()),
+			domain.Field("error", err.Error()),
+		)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		backoff = min(backoff*2, MAX_CONNECT_BACKOFF)
+	}
+
+	return nil
+}
+
+// dsn собирает строку подключения libpq, значения экранируются
+func dsn(ip, port, user, pass, nameDB string, opts domain.DbOptions) string {
+	sslMode := opts.SSLMode
+	if sslMode == "" {
+		sslMode = domain.SSL_DISABLE
+	}
+
+	params := [][2]string{
+		{"host", ip},
+		{"port", port},
+		{"user", user},
+		{"password", pass},
+		{"dbname", nameDB},
+		{"sslmode", sslMode},
+		{"sslrootcert", opts.SSLRootCert},
+		{"sslcert", opts.SSLCert},
+		{"sslkey", opts.SSLKey},
+	}
+
+	parts := make([]string, 0, len(params))
+	for _, param := range params {
+		if param[1] == "" {
+			continue
+		}
+
+		value := strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(param[1])
+		parts = append(parts, fmt.Sprintf("%s='%s'", param[0], value))
+	}
+
+	return strings.Join(parts, " ")
 }
 
-// CloseDB закрывает подключение к базе данных
+// CloseDB закрывает подключение к базе данных и к репликам
 func (db *DB) CloseDB() error {
 	db.Logger.Debug("Closing database connection")
-	return db.Db.Close()
+	return errors.Join(db.Db.Close(), db.closeReplicas())
 }
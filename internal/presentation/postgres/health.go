@@ -0,0 +1,32 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// Ping проверяет доступность базы данных
+func (db *DB) Ping(ctx context.Context) error {
+	return db.Db.PingContext(ctx)
+}
+
+// SchemaVersion возвращает версию примененных миграций из таблицы golang-migrate.
+// Если миграции еще не применялись, возвращается версия 0
+func (db *DB) SchemaVersion(ctx context.Context) (uint, bool, error) {
+	var (
+		version int64
+		dirty   bool
+	)
+
+	err := db.Db.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+
+	if err != nil {
+		return 0, false, err
+	}
+
+	return uint(version), dirty, nil
+}
@@ -1,48 +1,90 @@
 package postgres
 
 import (
-	"fmt"
+	"context"
+	"database/sql"
+	"errors"
+	"merch/internal/domain"
 	e "merch/internal/presentation/customError"
-	"net/http"
+	"merch/internal/presentation/migrations"
 
 	"github.com/golang-migrate/migrate/v4"
-	"github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/golang-migrate/migrate/v4/database/pgx/v5"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
 )
 
-// CreateSchema выполняет миграции базы данных для создания схемы
-func (db *DB) CreateSchema() error {
-	db.Logger.Debug("Migrating...")
+// SCHEMA_VERSION - версия последней миграции в internal/presentation/migrations, обновляется вместе с новой миграцией
+const SCHEMA_VERSION = 8
 
-	// Создаем экземпляр драйвера для PostgreSQL
-	driver, err := postgres.WithInstance(db.Db, &postgres.Config{})
+// MIGRATION_CONNS - размер пула мигратора: одно соединение держит блокировку и выполняет миграции, второе - служебные запросы драйвера
+const MIGRATION_CONNS = 2
+
+// Migrator создает мигратор со встроенными миграциями на отдельном пуле подключений.
+// Драйвер pgx закрывает пул вместе с мигратором, поэтому основной пул DB остается открытым
+func (db *DB) Migrator(ctx context.Context) (*migrate.Migrate, error) {
+	if db.connector == nil {
+		return nil, migratingError("Creating migrator error", errors.New("database is not opened by CreateDB"))
+	}
+
+	source, err := iofs.New(migrations.FS, ".")
 	if err != nil {
-		db.Logger.Error(fmt.Sprintf("Creating driver PostgreSQL fatal error: %v", err))
-		return &e.MigratingError{
-			Code: http.StatusInternalServerError,
-			Err:  "Creating driver PostgreSQL error",
-		}
+		return nil, migratingError("Reading embedded migrations error", err)
+	}
+
+	conn := sql.OpenDB(db.connector)
+	conn.SetMaxOpenConns(MIGRATION_CONNS)
+
+	err = conn.PingContext(ctx)
+	if err != nil {
+		_ = conn.Close()
+		return nil, migratingError("Getting database connection error", err)
 	}
 
-	// Создаем мигратор с указанным источником миграций и базой данных
-	m, err := migrate.NewWithDatabaseInstance("file:/../../../internal/presentation/migrations", "postgres", driver)
+	driver, err := pgx.WithInstance(conn, &pgx.Config{})
 	if err != nil {
-		db.Logger.Error(fmt.Sprintf("Creating migrator error: %v", err))
-		return &e.MigratingError{
-			Code: http.StatusInternalServerError,
-			Err:  "Creating migrator error",
-		}
+		_ = conn.Close()
+		return nil, migratingError("Creating driver PostgreSQL error", err)
 	}
 
-	// Применяем миграции к базе данных
-	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
-		db.Logger.Error(fmt.Sprintf("Error applying migrations: %v", err))
-		return &e.MigratingError{
-			Code: http.StatusInternalServerError,
-			Err:  "Error applying migrations",
+	m, err := migrate.NewWithInstance("iofs", source, "pgx5", driver)
+	if err != nil {
+		_ = driver.Close()
+		return nil, migratingError("Creating migrator error", err)
+	}
+
+	return m, nil
+}
+
+// CreateSchema применяет все новые миграции базы данных
+func (db *DB) CreateSchema() error {
+	db.Logger.Debug("Migrating...")
+
+	m, err := db.Migrator(context.Background())
+	if err != nil {
+		db.Logger.Error(err.Error())
+		return err
+	}
+	defer func() {
+		sourceErr, dbErr := m.Close()
+		if err := errors.Join(sourceErr, dbErr); err != nil {
+			db.Logger.Error("Closing migrator error", domain.Field("error", err.Error()))
 		}
+	}()
+
+	// Применяем миграции к базе данных
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		db.Logger.Error("Error applying migrations", domain.Field("error", err.Error()))
+		return migratingError("Error applying migrations", err)
 	}
 
 	db.Logger.Debug("Migrations successfully applied!")
 	return nil
 }
+
+func migratingError(msg string, err error) error {
+	return &e.MigratingError{
+		Kind:  domain.ErrInternal,
+		Err:   msg,
+		Cause: err,
+	}
+}
@@ -1,11 +1,22 @@
 package postgres
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"io/fs"
+	"merch/internal/domain"
+	"merch/internal/presentation/migrations"
+	"merch/internal/presentation/seeds"
 	"merch/test/mocks"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 func TestCreateDB(t *testing.T) {
@@ -13,13 +24,50 @@ func TestCreateDB(t *testing.T) {
 	mockLogger.On("Debug", "Database connection creating...").Once()
 	mockLogger.On("Info", "Database connection has been created").Once()
 
-	db, err := CreateDB("localhost", "5432", "user", "password", "dbname", mockLogger)
+	db, err := CreateDB("localhost", "5432", "user", "password", "dbname", domain.DbOptions{MaxOpenConns: 5}, mockLogger, nil)
 	assert.NoError(t, err)
 	assert.NotNil(t, db)
 
 	mockLogger.AssertExpectations(t)
 }
 
+func TestConnect_Retry(t *testing.T) {
+	mockDB, sqlMock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	logger := new(mocks.LoggerRepo)
+	logger.On("Warn", "Database is not available, retrying", mock.Anything)
+	db := &DB{Db: mockDB, Logger: logger}
+
+	// База данных становится доступной с третьей попытки
+	sqlMock.ExpectPing().WillReturnError(errors.New("connection refused"))
+	sqlMock.ExpectPing().WillReturnError(errors.New("connection refused"))
+	sqlMock.ExpectPing()
+	require.NoError(t, db.connect(context.Background(), 3, time.Millisecond))
+	logger.AssertNumberOfCalls(t, "Warn", 2)
+
+	// После последней попытки возвращается ошибка подключения
+	sqlMock.ExpectPing().WillReturnError(errors.New("connection refused"))
+	sqlMock.ExpectPing().WillReturnError(errors.New("the database system is starting up"))
+	err = db.connect(context.Background(), 2, time.Millisecond)
+	assert.EqualError(t, err, "the database system is starting up")
+
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestDSN(t *testing.T) {
+	assert.Equal(t,
+		`host='db' port='5432' user='merch' password='p\'a ss' dbname='merch' sslmode='disable'`,
+		dsn("db", "5432", "merch", "p'a ss", "merch", domain.DbOptions{}),
+	)
+
+	assert.Equal(t,
+		`host='db' port='5432' user='merch' dbname='merch' sslmode='verify-full' sslrootcert='/certs/ca.pem' sslcert='/certs/client.pem' sslkey='/certs/client.key'`,
+		dsn("db", "5432", "merch", "", "merch", domain.DbOptions{SSLMode: domain.SSL_VERIFY_FULL, SSLRootCert: "/certs/ca.pem", SSLCert: "/certs/client.pem", SSLKey: "/certs/client.key"}),
+	)
+}
+
 func TestCloseDB_Success(t *testing.T) {
 	mockLogger := new(mocks.LoggerRepo)
 	mockLogger.On("Debug", "Closing database connection").Once()
@@ -50,3 +98,170 @@ func TestCloseDB_Success(t *testing.T) {
 		return
 	}
 }
+
+func TestSchemaVersion(t *testing.T) {
+	mockDB, sqlMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer mockDB.Close()
+
+	db := &DB{Db: mockDB, Logger: new(mocks.LoggerRepo)}
+
+	sqlMock.ExpectQuery("SELECT version, dirty FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}).AddRow(7, false))
+
+	version, dirty, err := db.SchemaVersion(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, uint(7), version)
+	assert.False(t, dirty)
+
+	sqlMock.ExpectQuery("SELECT version, dirty FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}))
+
+	version, _, err = db.SchemaVersion(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, uint(0), version)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	source, err := iofs.New(migrations.FS, ".")
+	require.NoError(t, err)
+
+	// У каждой встроенной миграции есть откат, а последняя версия совпадает с SCHEMA_VERSION
+	version, err := source.First()
+	require.NoError(t, err)
+	for {
+		_, _, err = source.ReadDown(version)
+		assert.NoError(t, err, "migration %d has no down file", version)
+
+		next, err := source.Next(version)
+		if errors.Is(err, fs.ErrNotExist) {
+			break
+		}
+		require.NoError(t, err)
+		version = next
+	}
+
+	assert.Equal(t, uint(SCHEMA_VERSION), version)
+}
+
+func TestSeedSets(t *testing.T) {
+	// Наборы, которые принимает конфигурация, совпадают со встроенными директориями
+	assert.ElementsMatch(t, domain.SEED_SETS, seeds.Sets())
+	for _, set := range domain.SEED_SETS {
+		files, err := seeds.Files(set)
+		require.NoError(t, err)
+		assert.NotEmpty(t, files, set)
+	}
+}
+
+func TestSeed(t *testing.T) {
+	mockDB, sqlMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	logger := new(mocks.LoggerRepo)
+	logger.On("Info", "Seed applied", mock.Anything).Once()
+	logger.On("Error", "Seeding error", mock.Anything).Twice()
+	db := &DB{Db: mockDB, Logger: logger}
+
+	// Каталог обновляет цены существующих товаров вместо ошибки о дубликате
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectExec(`INSERT INTO Subject .* ON CONFLICT \(name\) DO UPDATE`).WillReturnResult(sqlmock.NewResult(0, 10))
+	sqlMock.ExpectCommit()
+	require.NoError(t, db.Seed(context.Background(), domain.SEED_CATALOG))
+
+	// Ошибка в файле откатывает весь набор
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectExec(`INSERT INTO Users`).WillReturnError(errors.New("relation users does not exist"))
+	sqlMock.ExpectRollback()
+	err = db.Seed(context.Background(), domain.SEED_DEMO)
+	assert.ErrorIs(t, err, domain.ErrInternal)
+
+	// Неизвестный набор не трогает базу данных
+	err = db.Seed(context.Background(), "prod")
+	assert.ErrorIs(t, err, domain.ErrInvalidInput)
+
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+	logger.AssertExpectations(t)
+}
+
+func TestReader(t *testing.T) {
+	logger := new(mocks.LoggerRepo)
+	logger.On("Info", "Read replicas enabled", mock.Anything)
+
+	primary, _, err := sqlmock.New()
+	require.NoError(t, err)
+	first, _, err := sqlmock.New()
+	require.NoError(t, err)
+	second, _, err := sqlmock.New()
+	require.NoError(t, err)
+
+	db := &DB{Db: primary, Logger: logger, addr: "primary:5432"}
+	ctx := context.Background()
+	assert.Same(t, primary, db.Reader(ctx), "without replicas reads go to the primary")
+	assert.Equal(t, map[string]*sql.DB{"primary:5432": primary}, db.Pools())
+
+	db.UseReplicas([]*DB{{Db: first, Logger: logger, addr: "first:5432"}, {Db: second, Logger: logger, addr: "second:5432"}}, time.Minute)
+	assert.Same(t, first, db.Reader(ctx))
+	assert.Same(t, second, db.Reader(ctx))
+	assert.Same(t, first, db.Reader(ctx))
+	assert.Same(t, primary, db.Reader(WithPrimary(ctx)))
+	assert.Equal(t, map[string]*sql.DB{"primary:5432": primary, "first:5432": first, "second:5432": second}, db.Pools())
+}
+
+func TestMarkWrite(t *testing.T) {
+	logger := new(mocks.LoggerRepo)
+	logger.On("Info", "Read replicas enabled", mock.Anything)
+
+	primary, _, err := sqlmock.New()
+	require.NoError(t, err)
+	replica, _, err := sqlmock.New()
+	require.NoError(t, err)
+
+	db := &DB{Db: primary, Logger: logger}
+	ctx := context.Background()
+	db.MarkWrite(1)
+	assert.Equal(t, ctx, db.ReadContext(ctx, 1), "without replicas the context is unchanged")
+
+	db.UseReplicas([]*DB{{Db: replica, Logger: logger}}, 50*time.Millisecond)
+	db.MarkWrite(1)
+	assert.Same(t, primary, db.Reader(db.ReadContext(ctx, 1)), "the writer reads its own writes from the primary")
+	assert.Same(t, replica, db.Reader(db.ReadContext(ctx, 2)), "other users read from the replica")
+
+	time.Sleep(60 * time.Millisecond)
+	assert.Same(t, replica, db.Reader(db.ReadContext(ctx, 1)), "after the window the writer reads from the replica again")
+}
+
+func TestCloseDB_Replicas(t *testing.T) {
+	logger := new(mocks.LoggerRepo)
+	logger.On("Debug", "Closing database connection")
+	logger.On("Info", "Read replicas enabled", mock.Anything)
+
+	primary, primaryMock, err := sqlmock.New()
+	require.NoError(t, err)
+	replica, replicaMock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	primaryMock.ExpectClose()
+	replicaMock.ExpectClose().WillReturnError(errors.New("replica is gone"))
+
+	db := &DB{Db: primary, Logger: logger}
+	db.UseReplicas([]*DB{{Db: replica, Logger: logger}}, time.Second)
+
+	err = db.CloseDB()
+	assert.ErrorContains(t, err, "replica is gone")
+	assert.NoError(t, primaryMock.ExpectationsWereMet())
+	assert.NoError(t, replicaMock.ExpectationsWereMet())
+}
+
+func TestMigrator_RequiresCreateDB(t *testing.T) {
+	mockDB, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	db := &DB{Db: mockDB, Logger: new(mocks.LoggerRepo)}
+	_, err = db.Migrator(context.Background())
+	assert.ErrorIs(t, err, domain.ErrInternal)
+	assert.ErrorContains(t, err, "database is not opened by CreateDB")
+}
@@ -0,0 +1,157 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"merch/internal/domain"
+	"merch/internal/interfaces"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// STICKY_PRUNE_SIZE - после какого числа запомненных пользователей из списка удаляются устаревшие записи
+const STICKY_PRUNE_SIZE = 1024
+
+type primaryKey struct{}
+
+// WithPrimary помечает контекст: запросы на чтение с ним выполняются на основном сервере, даже если есть реплики.
+// Нужен, когда чтение должно видеть только что записанные данные, например проверка баланса перед списанием
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// UsesPrimary сообщает, помечен ли контекст через WithPrimary
+func UsesPrimary(ctx context.Context) bool {
+	primary, _ := ctx.Value(primaryKey{}).(bool)
+	return primary
+}
+
+// replicas - реплики для чтения и пользователи, которые недавно изменили данные
+type replicas struct {
+	pools  []*DB
+	next   atomic.Uint64
+	sticky time.Duration
+
+	mu     sync.Mutex
+	writes map[domain.UserId]time.Time
+}
+
+// ConnectReplicas создает пулы подключений к репликам с теми же пользователем, базой и параметрами, что и у основного сервера.
+// Адрес реплики указывается как host или host:port, без порта используется port
+func ConnectReplicas(hosts []string, port, user, pass, nameDB string, opts domain.DbOptions, logger interfaces.LoggerRepo, tracer trace.TracerProvider) ([]*DB, error) {
+	pools := make([]*DB, 0, len(hosts))
+	for _, host := range hosts {
+		replicaHost, replicaPort, err := net.SplitHostPort(host)
+		if err != nil {
+			replicaHost, replicaPort = host, port
+		}
+
+		pool, err := CreateDB(replicaHost, replicaPort, user, pass, nameDB, opts, logger.With(domain.Field("replica", host)), tracer)
+		if err != nil {
+			for _, opened := range pools {
+				_ = opened.CloseDB()
+			}
+			return nil, err
+		}
+
+		pools = append(pools, pool)
+	}
+
+	return pools, nil
+}
+
+// UseReplicas направляет запросы на чтение, выбранные через Reader, на реплики по кругу.
+// Пользователь, который изменил данные через MarkWrite, читает с основного сервера в течение sticky
+func (db *DB) UseReplicas(pools []*DB, sticky time.Duration) {
+	if len(pools) == 0 {
+		db.read = nil
+		return
+	}
+
+	db.read = &replicas{
+		pools:  pools,
+		sticky: sticky,
+		writes: make(map[domain.UserId]time.Time),
+	}
+	db.Logger.Info("Read replicas enabled", domain.Field("replicas", len(pools)), domain.Field("sticky", sticky.String()))
+}
+
+// Reader возвращает подключение для запроса только на чтение: следующую реплику
+// или основной сервер, если реплик нет или контекст помечен через WithPrimary
+func (db *DB) Reader(ctx context.Context) *sql.DB {
+	if db.read == nil || UsesPrimary(ctx) {
+		return db.Db
+	}
+
+	next := db.read.next.Add(1) - 1
+	return db.read.pools[next%uint64(len(db.read.pools))].Db
+}
+
+// MarkWrite запоминает, что пользователь изменил данные, чтобы его следующие запросы видели изменения
+func (db *DB) MarkWrite(userId domain.UserId) {
+	if db.read == nil || db.read.sticky <= 0 {
+		return
+	}
+
+	now := time.Now()
+	db.read.mu.Lock()
+	defer db.read.mu.Unlock()
+
+	if len(db.read.writes) >= STICKY_PRUNE_SIZE {
+		for id, until := range db.read.writes {
+			if now.After(until) {
+				delete(db.read.writes, id)
+			}
+		}
+	}
+
+	db.read.writes[userId] = now.Add(db.read.sticky)
+}
+
+// ReadContext помечает контекст через WithPrimary, если пользователь недавно изменил данные
+func (db *DB) ReadContext(ctx context.Context, userId domain.UserId) context.Context {
+	if db.read == nil {
+		return ctx
+	}
+
+	db.read.mu.Lock()
+	until, ok := db.read.writes[userId]
+	db.read.mu.Unlock()
+
+	if ok && time.Now().Before(until) {
+		return WithPrimary(ctx)
+	}
+
+	return ctx
+}
+
+// Pools возвращает пулы соединений основного сервера и реплик по адресам host:port
+func (db *DB) Pools() map[string]*sql.DB {
+	pools := map[string]*sql.DB{db.addr: db.Db}
+	if db.read != nil {
+		for _, pool := range db.read.pools {
+			pools[pool.addr] = pool.Db
+		}
+	}
+
+	return pools
+}
+
+// closeReplicas закрывает подключения к репликам
+func (db *DB) closeReplicas() error {
+	if db.read == nil {
+		return nil
+	}
+
+	var errs []error
+	for _, pool := range db.read.pools {
+		errs = append(errs, pool.CloseDB())
+	}
+
+	return errors.Join(errs...)
+}
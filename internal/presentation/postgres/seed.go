@@ -0,0 +1,75 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"merch/internal/domain"
+	e "merch/internal/presentation/customError"
+	"merch/internal/presentation/seeds"
+)
+
+// Seed применяет наборы начальных данных из internal/presentation/seeds в указанном порядке.
+// Каждый набор применяется в отдельной транзакции, повторное применение не создает дубликатов
+func (db *DB) Seed(ctx context.Context, sets ...string) error {
+	for _, set := range sets {
+		err := db.seedSet(ctx, set)
+		if err != nil {
+			db.Logger.Error("Seeding error", domain.Field("set", set), domain.Field("error", err.Error()))
+			return err
+		}
+	}
+
+	return nil
+}
+
+// seedSet применяет файлы одного набора
+func (db *DB) seedSet(ctx context.Context, set string) error {
+	files, err := seeds.Files(set)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &e.SeedError{
+			Kind: domain.ErrInvalidInput,
+			Err:  fmt.Sprintf("Unknown seed set %q", set),
+		}
+	}
+	if err != nil {
+		return seedError("Reading seed files error", err)
+	}
+
+	tx, err := db.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return seedError("Starting seed transaction error", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	for _, file := range files {
+		query, err := fs.ReadFile(seeds.FS, file)
+		if err != nil {
+			return seedError("Reading seed file error", err)
+		}
+
+		_, err = tx.ExecContext(ctx, string(query))
+		if err != nil {
+			return seedError("Applying seed file "+file+" error", err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return seedError("Committing seed transaction error", err)
+	}
+
+	db.Logger.Info("Seed applied", domain.Field("set", set), domain.Field("files", len(files)))
+	return nil
+}
+
+func seedError(msg string, err error) error {
+	return &e.SeedError{
+		Kind:  domain.ErrInternal,
+		Err:   msg,
+		Cause: err,
+	}
+}
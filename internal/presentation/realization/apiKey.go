@@ -0,0 +1,197 @@
+package realization
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"merch/internal/domain"
+	"merch/internal/interfaces"
+	e "merch/internal/presentation/customError"
+	"time"
+)
+
+// ApiKey - структура для работы с API-ключами
+type ApiKey struct {
+	db      *sql.DB
+	logger  interfaces.LoggerRepo
+	timeout time.Duration
+}
+
+// NewApiKey создает новый экземпляр ApiKey с подключением к базе данных и временем выполнения одного запроса
+func NewApiKey(db *sql.DB, logger interfaces.LoggerRepo, timeout time.Duration) *ApiKey {
+	return &ApiKey{
+		db:      db,
+		logger:  logger,
+		timeout: timeout,
+	}
+}
+
+// rowScanner - общий интерфейс для sql.Row и sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// Create сохраняет новый ключ
+func (s *ApiKey) Create(ctx context.Context, key domain.ApiKey) (*uint64, error) {
+	s.logger.Debug("Creating API key")
+	var id uint64
+	ctx, cancel := queryContext(ctx, s.timeout)
+	defer cancel()
+	err := s.db.QueryRowContext(ctx, `INSERT INTO ApiKey ("name", "prefix", "hash", "scopes", "created_by", "expires_at") VALUES ($1, $2, $3, $4, $5, $6) RETURNING "id"`,
+		key.Name, key.Prefix, key.Hash, domain.JoinScopes(key.Scopes), key.CreatedBy, key.ExpiresAt).Scan(&id)
+
+	if err != nil {
+		return nil, &e.DbQueryError{
+			Kind:  domain.ErrInternal,
+			Err:   "Query db error",
+			Cause: err,
+		}
+	}
+
+	return &id, nil
+}
+
+// GetByHash получает ключ по хэшу
+func (s *ApiKey) GetByHash(ctx context.Context, hash string) (*domain.ApiKey, error) {
+	s.logger.Debug("Getting API key")
+	ctx, cancel := queryContext(ctx, s.timeout)
+	defer cancel()
+	row := s.db.QueryRowContext(ctx, `SELECT "id", "name", "prefix", "hash", "scopes", "created_by", "created_at", "expires_at", "last_used_at", "revoked_at" FROM ApiKey WHERE "hash" = $1`, hash)
+
+	key, err := scanApiKey(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, &e.DbQueryError{
+			Kind:  domain.ErrInternal,
+			Err:   "Query db error",
+			Cause: err,
+		}
+	}
+
+	return key, nil
+}
+
+// List возвращает все выданные ключи
+func (s *ApiKey) List(ctx context.Context) ([]domain.ApiKey, error) {
+	s.logger.Debug("Listing API keys")
+	ctx, cancel := queryContext(ctx, s.timeout)
+	defer cancel()
+	rows, err := s.db.QueryContext(ctx, `SELECT "id", "name", "prefix", "hash", "scopes", "created_by", "created_at", "expires_at", "last_used_at", "revoked_at" FROM ApiKey ORDER BY "id"`)
+	if err != nil {
+		return nil, &e.DbQueryError{
+			Kind:  domain.ErrInternal,
+			Err:   "Query db error",
+			Cause: err,
+		}
+	}
+	defer func() {
+		err := rows.Close()
+		if err != nil {
+			s.logger.Error("Error closing rows", domain.Field("error", err.Error()))
+		}
+	}()
+
+	keys := []domain.ApiKey{}
+	for rows.Next() {
+		key, err := scanApiKey(rows)
+		if err != nil {
+			return nil, &e.DbQueryError{
+				Kind:  domain.ErrInternal,
+				Err:   "Scanning API key error",
+				Cause: err,
+			}
+		}
+		keys = append(keys, *key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, &e.DbQueryError{
+			Kind:  domain.ErrInternal,
+			Err:   "Query db error",
+			Cause: err,
+		}
+	}
+
+	return keys, nil
+}
+
+// Touch обновляет дату последнего использования ключа
+func (s *ApiKey) Touch(ctx context.Context, id uint64) error {
+	ctx, cancel := queryContext(ctx, s.timeout)
+	defer cancel()
+	_, err := s.db.ExecContext(ctx, `UPDATE ApiKey SET "last_used_at" = NOW() WHERE "id" = $1`, id)
+
+	if err != nil {
+		return &e.DbQueryError{
+			Kind:  domain.ErrInternal,
+			Err:   "Query db error",
+			Cause: err,
+		}
+	}
+
+	return nil
+}
+
+// Revoke отзывает ключ
+func (s *ApiKey) Revoke(ctx context.Context, id uint64) error {
+	s.logger.Debug("Revoking API key")
+	ctx, cancel := queryContext(ctx, s.timeout)
+	defer cancel()
+	result, err := s.db.ExecContext(ctx, `UPDATE ApiKey SET "revoked_at" = NOW() WHERE "id" = $1 AND "revoked_at" IS NULL`, id)
+
+	if err != nil {
+		return &e.DbQueryError{
+			Kind:  domain.ErrInternal,
+			Err:   "Query db error",
+			Cause: err,
+		}
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return &e.DbQueryError{
+			Kind:  domain.ErrInternal,
+			Err:   "Query db error",
+			Cause: err,
+		}
+	}
+
+	if updated == 0 {
+		return &e.RowsNotFoundError{
+			Kind: domain.ErrInvalidInput,
+			Err:  "API key not exists or already revoked",
+		}
+	}
+
+	return nil
+}
+
+// scanApiKey считывает ключ из строки результата запроса
+func scanApiKey(row rowScanner) (*domain.ApiKey, error) {
+	var key domain.ApiKey
+	var scopes string
+	var expiresAt, lastUsedAt, revokedAt sql.NullTime
+
+	err := row.Scan(&key.Id, &key.Name, &key.Prefix, &key.Hash, &scopes, &key.CreatedBy, &key.CreatedAt, &expiresAt, &lastUsedAt, &revokedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	key.Scopes = domain.SplitScopes(scopes)
+	key.ExpiresAt = nullTime(expiresAt)
+	key.LastUsedAt = nullTime(lastUsedAt)
+	key.RevokedAt = nullTime(revokedAt)
+
+	return &key, nil
+}
+
+func nullTime(value sql.NullTime) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+
+	return &value.Time
+}
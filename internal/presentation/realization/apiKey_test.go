@@ -0,0 +1,33 @@
+package realization
+
+import (
+	"context"
+	"errors"
+	"merch/internal/domain"
+	"merch/test/mocks"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestApiKey_ListRowsError(t *testing.T) {
+	mockLogger := new(mocks.LoggerRepo)
+	mockLogger.On("Debug", mock.Anything).Maybe()
+	db, sqlMock := newSqlMock(t)
+	repo := NewApiKey(db, mockLogger, 50*time.Millisecond)
+
+	// Ошибка посреди чтения строк не должна превращаться в неполный список
+	rows := sqlmock.NewRows([]string{"id", "name", "prefix", "hash", "scopes", "created_by", "created_at", "expires_at", "last_used_at", "revoked_at"}).
+		AddRow(1, "hr-bot", "mk_a", "hash-a", "coins:grant", 1, time.Now(), nil, nil, nil).
+		AddRow(2, "crm", "mk_b", "hash-b", "balance:read", 1, time.Now(), nil, nil, nil).
+		RowError(1, errors.New("connection reset"))
+	sqlMock.ExpectQuery(`SELECT "id", "name"`).WillReturnRows(rows)
+
+	keys, err := repo.List(context.Background())
+	assert.ErrorIs(t, err, domain.ErrInternal)
+	assert.Nil(t, keys)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
@@ -2,45 +2,62 @@ package realization
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"errors"
-	"fmt"
 	"merch/internal/domain"
+	"merch/internal/interfaces"
 	e "merch/internal/presentation/customError"
-	"merch/internal/presentation/postgres"
-	"net/http"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
 )
 
+// Назначения JWT, отличные от доступа к API
+const (
+	PURPOSE_CHALLENGE = "2fa" // Токен подтверждения для второго шага входа
+)
+
+// CHALLENGE_TTL - срок действия токена подтверждения
+const CHALLENGE_TTL = 5 * time.Minute
+
 // Token - структура для JWT токена
 type Token struct {
-	Id    uint64 `json:"id"`
-	Email string `json:"email"`
+	Id      uint64 `json:"id"`
+	Email   string `json:"email"`
+	Role    string `json:"role"`
+	Purpose string `json:"purpose,omitempty"` // Пустое для токенов доступа
 	jwt.StandardClaims
 }
 
 // Auth - структуру для работы с авторизацией
 type Auth struct {
+	db        *sql.DB
+	logger    interfaces.LoggerRepo
+	timeout   time.Duration
 	secretKey string
 }
 
-// NewAuth создает новый экземпляр Auth с заданным секретным ключом
-func NewAuth(secret string) *Auth {
-	LoggerService.Debug("Creating auth service")
+// NewAuth создает новый экземпляр Auth с подключением к базе данных, временем выполнения запроса и заданным секретным ключом
+func NewAuth(db *sql.DB, logger interfaces.LoggerRepo, timeout time.Duration, secret string) *Auth {
+	logger.Debug("Creating auth service")
 	return &Auth{
+		db:        db,
+		logger:    logger,
+		timeout:   timeout,
 		secretKey: secret,
 	}
 }
 
 // CreateToken создает JWT токен для данного email
-func (s *Auth) CreateToken(data domain.AuthorizationData) (*domain.Token, error) {
-	LoggerService.Debug("Creating JWT")
+func (s *Auth) CreateToken(ctx context.Context, data domain.AuthorizationData) (*domain.Token, error) {
+	s.logger.Debug("Creating JWT")
 	expires := time.Now().Add(time.Hour * 24 * 30) // Срок действия токена - 30 дней
 	claims := &Token{
 		Id:    data.Id,
 		Email: data.Username,
+		Role:  data.Role,
 		StandardClaims: jwt.StandardClaims{
 			ExpiresAt: expires.Unix(),
 		},
@@ -51,36 +68,75 @@ func (s *Auth) CreateToken(data domain.AuthorizationData) (*domain.Token, error)
 
 	if err != nil {
 		return nil, &e.JWTGenerationError{
-			Code: http.StatusInternalServerError,
-			Err:  fmt.Sprintf("Token generation error: %v", err),
+			Kind:  domain.ErrInternal,
+			Err:   "Token generation error",
+			Cause: err,
 		}
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	deleteCtx, cancel := queryContext(ctx, s.timeout)
 	defer cancel()
-	_, err = postgres.DbService.Db.ExecContext(ctx, ` DELETE FROM Token WHERE "user_id" = $1 `, data.Id)
+	_, err = s.db.ExecContext(deleteCtx, ` DELETE FROM Token WHERE "user_id" = $1 `, data.Id)
 
 	if err != nil {
-		LoggerService.Error("Deleting token error")
+		s.logger.Error("Deleting token error")
 	}
 
-	ctx, cancel = context.WithTimeout(context.Background(), time.Second*3)
+	insertCtx, cancel := queryContext(ctx, s.timeout)
 	defer cancel()
-	_, err = postgres.DbService.Db.ExecContext(ctx, `INSERT INTO Token("user_id", "value") VALUES ($1, $2)`, data.Id, tokenS)
+	_, err = s.db.ExecContext(insertCtx, `INSERT INTO Token("user_id", "value") VALUES ($1, $2)`, data.Id, hashToken(tokenS))
 
 	if err != nil {
 		return nil, &e.DbQueryError{
-			Code: http.StatusInternalServerError,
-			Err:  fmt.Sprintf("Database query error: %v", err),
+			Kind:  domain.ErrInternal,
+			Err:   "Database query error",
+			Cause: err,
 		}
 	}
 
 	return &tokenS, nil
 }
 
-// DecodeToken декодирует JWT токен
+// DecodeToken декодирует JWT токен доступа
 func (s *Auth) DecodeToken(tokenStr domain.Token) (*domain.AuthorizationToken, error) {
-	LoggerService.Debug("Decoding JWT")
+	s.logger.Debug("Decoding JWT")
+	return s.decode(tokenStr, "")
+}
+
+// CreateChallenge создает короткоживущий токен подтверждения для второго шага входа.
+// Токен не сохраняется в базе данных и не принимается как токен доступа
+func (s *Auth) CreateChallenge(data domain.AuthorizationData) (*domain.Token, error) {
+	s.logger.Debug("Creating challenge JWT")
+	claims := &Token{
+		Id:      data.Id,
+		Email:   data.Username,
+		Role:    data.Role,
+		Purpose: PURPOSE_CHALLENGE,
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: time.Now().Add(CHALLENGE_TTL).Unix(),
+		},
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(s.secretKey))
+	if err != nil {
+		return nil, &e.JWTGenerationError{
+			Kind:  domain.ErrInternal,
+			Err:   "Token generation error",
+			Cause: err,
+		}
+	}
+
+	return &token, nil
+}
+
+// DecodeChallenge декодирует токен подтверждения
+func (s *Auth) DecodeChallenge(tokenStr domain.Token) (*domain.AuthorizationToken, error) {
+	s.logger.Debug("Decoding challenge JWT")
+	return s.decode(tokenStr, PURPOSE_CHALLENGE)
+}
+
+// decode проверяет подпись и назначение JWT
+func (s *Auth) decode(tokenStr domain.Token, purpose string) (*domain.AuthorizationToken, error) {
 	claims := &Token{}
 	token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
 		return []byte(s.secretKey), nil
@@ -88,34 +144,40 @@ func (s *Auth) DecodeToken(tokenStr domain.Token) (*domain.AuthorizationToken, e
 
 	if err != nil {
 		return nil, &e.NeedAuthorization{
-			Code: http.StatusUnauthorized,
+			Kind: domain.ErrUnauthorized,
 			Err:  "Bad token's signature",
 		}
 	}
 
-	if !token.Valid {
+	if !token.Valid || claims.Purpose != purpose {
 		return nil, &e.NeedAuthorization{
-			Code: http.StatusUnauthorized,
+			Kind: domain.ErrUnauthorized,
 			Err:  "Invalid token",
 		}
 	}
 
+	// Токены, выданные до появления ролей, не содержат роль
+	if claims.Role == "" {
+		claims.Role = domain.ROLE_USER
+	}
+
 	return &domain.AuthorizationToken{
 		Id:    claims.Id,
 		Email: claims.Email,
+		Role:  claims.Role,
 	}, nil
 }
 
 // Access проверяет доступ по токену для указанного пользователя
-func (s *Auth) Access(token domain.Token, userId domain.UserId) (*domain.SuccessfulAuth, error) {
-	LoggerService.Debug("Checking user's access")
+func (s *Auth) Access(ctx context.Context, token domain.Token, userId domain.UserId) (*domain.SuccessfulAuth, error) {
+	s.logger.Debug("Checking user's access")
 	var notExists = false
 	var exists = true
 
 	var id uint64
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	ctx, cancel := queryContext(ctx, s.timeout)
 	defer cancel()
-	err := postgres.DbService.Db.QueryRowContext(ctx, `SELECT "user_id" FROM Token WHERE "value" = $1`, token).Scan(&id)
+	err := s.db.QueryRowContext(ctx, `SELECT "user_id" FROM Token WHERE "value" = $1`, hashToken(token)).Scan(&id)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -123,8 +185,9 @@ func (s *Auth) Access(token domain.Token, userId domain.UserId) (*domain.Success
 		}
 
 		return nil, &e.DbQueryError{
-			Code: http.StatusInternalServerError,
-			Err:  fmt.Sprintf("Database query error: %v", err),
+			Kind:  domain.ErrInternal,
+			Err:   "Database query error",
+			Cause: err,
 		}
 	}
 
@@ -134,3 +197,27 @@ func (s *Auth) Access(token domain.Token, userId domain.UserId) (*domain.Success
 
 	return &exists, nil
 }
+
+// Revoke отзывает все токены пользователя
+func (s *Auth) Revoke(ctx context.Context, userId domain.UserId) error {
+	s.logger.Debug("Revoking user's tokens")
+	ctx, cancel := queryContext(ctx, s.timeout)
+	defer cancel()
+	_, err := s.db.ExecContext(ctx, `DELETE FROM Token WHERE "user_id" = $1`, userId)
+
+	if err != nil {
+		return &e.DbQueryError{
+			Kind:  domain.ErrInternal,
+			Err:   "Database query error",
+			Cause: err,
+		}
+	}
+
+	return nil
+}
+
+// hashToken возвращает SHA-256 хэш токена, который хранится в базе данных вместо самого JWT
+func hashToken(token domain.Token) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
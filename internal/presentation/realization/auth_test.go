@@ -0,0 +1,94 @@
+package realization
+
+import (
+	"context"
+	"merch/internal/domain"
+	"merch/test/mocks"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setupAuth(t *testing.T) (*Auth, sqlmock.Sqlmock) {
+	mockLogger := new(mocks.LoggerRepo)
+	mockLogger.On("Debug", mock.Anything).Maybe()
+	mockLogger.On("Error", mock.Anything).Maybe()
+
+	mockDB, sqlMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		_ = mockDB.Close()
+	})
+
+	return NewAuth(mockDB, mockLogger, 50*time.Millisecond, "test-secret"), sqlMock
+}
+
+func TestHashToken(t *testing.T) {
+	hash := hashToken("token")
+
+	assert.Len(t, hash, 64)
+	assert.Equal(t, hash, hashToken("token"))
+	assert.NotEqual(t, hash, hashToken("another token"))
+}
+
+func TestAuth_CreateTokenStoresHash(t *testing.T) {
+	auth, sqlMock := setupAuth(t)
+
+	sqlMock.ExpectExec(`DELETE FROM Token`).WithArgs(uint64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlMock.ExpectExec(`INSERT INTO Token`).WithArgs(uint64(1), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+
+	token, err := auth.CreateToken(context.Background(), domain.AuthorizationData{Id: 1, Username: "user@example.com"})
+	assert.NoError(t, err)
+	assert.NotNil(t, token)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+
+	sqlMock.ExpectQuery(`SELECT "user_id" FROM Token`).
+		WithArgs(hashToken(*token)).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(uint64(1)))
+
+	access, err := auth.Access(context.Background(), *token, 1)
+	assert.NoError(t, err)
+	assert.True(t, *access)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestAuth_AccessUnknownToken(t *testing.T) {
+	auth, sqlMock := setupAuth(t)
+
+	sqlMock.ExpectQuery(`SELECT "user_id" FROM Token`).
+		WithArgs(hashToken("unknown")).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+
+	access, err := auth.Access(context.Background(), "unknown", 1)
+	assert.NoError(t, err)
+	assert.False(t, *access)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestAuth_AccessTimeout(t *testing.T) {
+	auth, sqlMock := setupAuth(t)
+
+	// Запрос дольше настроенного времени выполнения прерывается
+	sqlMock.ExpectQuery(`SELECT "user_id" FROM Token`).WillDelayFor(time.Second).WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(1))
+
+	start := time.Now()
+	_, err := auth.Access(context.Background(), "token", 1)
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestAuth_AccessCanceled(t *testing.T) {
+	auth, sqlMock := setupAuth(t)
+
+	// Отмена контекста запроса клиента отменяет и запрос к базе данных
+	sqlMock.ExpectQuery(`SELECT "user_id" FROM Token`).WillDelayFor(time.Second).WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(1))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := auth.Access(ctx, "token", 1)
+	assert.ErrorContains(t, err, context.Canceled.Error())
+}
@@ -0,0 +1,83 @@
+package realization
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Как часто удалять из памяти просроченные записи кэша
+const cacheCleanupInterval = time.Minute
+
+// cacheEntry - значение в кэше и время, до которого оно действительно
+type cacheEntry struct {
+	value   []byte
+	expires time.Time
+}
+
+// MemoryCache - кэш в памяти процесса. У каждого экземпляра сервиса свой кэш,
+// поэтому инвалидация видна только в том процессе, где произошла запись
+type MemoryCache struct {
+	mu          sync.Mutex
+	entries     map[string]cacheEntry
+	now         func() time.Time
+	lastCleanup time.Time
+}
+
+// NewMemoryCache создает новый экземпляр MemoryCache
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{
+		entries: make(map[string]cacheEntry),
+		now:     time.Now,
+	}
+}
+
+// Get возвращает значение по ключу и сообщает, найдено ли оно
+func (s *MemoryCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, exists := s.entries[key]
+	if !exists || !s.now().Before(entry.expires) {
+		return nil, false, nil
+	}
+
+	return entry.value, true, nil
+}
+
+// Set сохраняет значение по ключу на время ttl
+func (s *MemoryCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.cleanup(now)
+	s.entries[key] = cacheEntry{value: value, expires: now.Add(ttl)}
+	return nil
+}
+
+// Delete удаляет значения по ключам, отсутствующие ключи пропускаются
+func (s *MemoryCache) Delete(ctx context.Context, keys ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range keys {
+		delete(s.entries, key)
+	}
+	return nil
+}
+
+// cleanup удаляет просроченные записи, которые уже не будут прочитаны
+func (s *MemoryCache) cleanup(now time.Time) {
+	if now.Sub(s.lastCleanup) < cacheCleanupInterval {
+		return
+	}
+
+	for key, entry := range s.entries {
+		if !now.Before(entry.expires) {
+			delete(s.entries, key)
+		}
+	}
+
+	s.lastCleanup = now
+}
@@ -0,0 +1,53 @@
+package realization
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryCache(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	cache := NewMemoryCache()
+	cache.now = func() time.Time { return now }
+
+	_, found, err := cache.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	require.NoError(t, cache.Set(ctx, "key", []byte("value"), time.Second))
+	require.NoError(t, cache.Set(ctx, "other", []byte("other"), time.Minute))
+	value, found, _ := cache.Get(ctx, "key")
+	assert.True(t, found)
+	assert.Equal(t, []byte("value"), value)
+
+	// Запись перестает читаться, когда истекает ttl
+	now = now.Add(time.Second)
+	_, found, _ = cache.Get(ctx, "key")
+	assert.False(t, found)
+
+	require.NoError(t, cache.Delete(ctx, "other", "missing"))
+	_, found, _ = cache.Get(ctx, "other")
+	assert.False(t, found)
+}
+
+func TestMemoryCache_Cleanup(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	cache := NewMemoryCache()
+	cache.now = func() time.Time { return now }
+
+	require.NoError(t, cache.Set(ctx, "short", []byte("value"), time.Second))
+	require.NoError(t, cache.Set(ctx, "long", []byte("value"), time.Hour))
+
+	// Просроченные записи удаляются из памяти при записи не чаще раза в минуту
+	now = now.Add(cacheCleanupInterval)
+	require.NoError(t, cache.Set(ctx, "new", []byte("value"), time.Second))
+	assert.Len(t, cache.entries, 2)
+	assert.Contains(t, cache.entries, "long")
+	assert.Contains(t, cache.entries, "new")
+}
@@ -0,0 +1,255 @@
+package realization
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"merch/internal/domain"
+	"merch/internal/interfaces"
+	"merch/internal/presentation/postgres"
+	"strconv"
+	"time"
+)
+
+// Имена кэшей, они же префиксы ключей и значения метки cache в метриках
+const (
+	CACHE_CATALOG   = "catalog"
+	CACHE_USER_INFO = "user_info"
+)
+
+// cacheHold - значение, которое invalidate оставляет вместо сброшенной записи. Пока оно не истекло,
+// загруженные значения не сохраняются: реплика может еще не получить запись и вернуть старые данные
+var cacheHold = []byte("\x00hold")
+
+// cacheSpace - часть общего кэша с префиксом ключей, временем жизни записей и метриками попаданий.
+// hold - сколько после сброса записи не кэшировать новые значения, 0 - только удалить запись
+type cacheSpace struct {
+	name    string
+	cache   interfaces.CacheRepo
+	ttl     time.Duration
+	hold    time.Duration
+	metrics interfaces.MetricsRepo
+	logger  interfaces.LoggerRepo
+}
+
+// key возвращает ключ кэша для идентификатора значения
+func (s cacheSpace) key(id string) string {
+	return s.name + ":" + id
+}
+
+// invalidate сбрасывает значения по идентификаторам, а если задан hold - заменяет их на cacheHold.
+// Ошибка кэша только логируется: запись в базу данных уже выполнена, а устаревшее значение пропадет через ttl
+func (s cacheSpace) invalidate(ctx context.Context, ids ...string) {
+	keys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, s.key(id))
+	}
+
+	var err error
+	if s.hold > 0 {
+		for _, key := range keys {
+			err = errors.Join(err, s.cache.Set(ctx, key, cacheHold, s.hold))
+		}
+	} else {
+		err = s.cache.Delete(ctx, keys...)
+	}
+
+	if err != nil {
+		s.logger.Warn("Cache invalidation error", domain.Field("cache", s.name), domain.Field("error", err.Error()))
+	}
+}
+
+// fetch возвращает значение из кэша или загружает его через load и сохраняет на ttl.
+// Ошибки кэша не прерывают запрос: значение загружается из базы данных. Ошибки load не кэшируются
+func fetch[T any](ctx context.Context, s cacheSpace, id string, load func() (*T, error)) (*T, error) {
+	if s.ttl <= 0 {
+		return load()
+	}
+
+	key := s.key(id)
+	cached := new(T)
+	found, held := s.get(ctx, key, cached)
+	if found {
+		s.metrics.CacheHit(s.name)
+		return cached, nil
+	}
+	s.metrics.CacheMiss(s.name)
+
+	value, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	if !held {
+		s.set(ctx, key, value)
+	}
+	return value, nil
+}
+
+// get читает значение по ключу в value и сообщает, найдено ли оно и не оставлен ли вместо него cacheHold
+func (s cacheSpace) get(ctx context.Context, key string, value any) (found, held bool) {
+	data, found, err := s.cache.Get(ctx, key)
+	if err != nil {
+		s.logger.Warn("Cache read error", domain.Field("cache", s.name), domain.Field("error", err.Error()))
+		return false, false
+	}
+
+	if found && bytes.Equal(data, cacheHold) {
+		return false, true
+	}
+
+	return found && json.Unmarshal(data, value) == nil, false
+}
+
+// set сохраняет значение по ключу на ttl
+func (s cacheSpace) set(ctx context.Context, key string, value any) {
+	data, err := json.Marshal(value)
+	if err == nil {
+		err = s.cache.Set(ctx, key, data, s.ttl)
+	}
+
+	if err != nil {
+		s.logger.Warn("Cache write error", domain.Field("cache", s.name), domain.Field("error", err.Error()))
+	}
+}
+
+// userInfoId возвращает идентификатор сводки пользователя в кэше
+func userInfoId(userId domain.UserId) string {
+	return strconv.FormatUint(userId, 10)
+}
+
+// invalidateUsers сбрасывает сводки пользователей по email. Пользователи, которых не удалось найти, пропускаются
+func invalidateUsers(ctx context.Context, users interfaces.UserRepo, userInfo cacheSpace, emails ...domain.UserEmail) {
+	ids := make([]string, 0, len(emails))
+	for _, email := range emails {
+		user, err := users.GetByEmail(ctx, email)
+		if err != nil || user == nil {
+			userInfo.logger.Warn("Cache invalidation error", domain.Field("cache", userInfo.name), domain.Field("email", email))
+			continue
+		}
+
+		ids = append(ids, userInfoId(user.Id))
+	}
+
+	userInfo.invalidate(ctx, ids...)
+}
+
+// CachedInventory кэширует предметы каталога и сбрасывает сводку пользователя после покупки.
+// Остальные методы InventoryRepo выполняются без кэша
+type CachedInventory struct {
+	interfaces.InventoryRepo
+	catalog  cacheSpace
+	userInfo cacheSpace
+}
+
+// NewCachedInventory создает новый экземпляр CachedInventory поверх inventory.
+// Предметы каталога хранятся в cache catalogTTL, 0 выключает кэширование каталога.
+// replicaLag - сколько после покупки не кэшировать сводку покупателя, пока реплики догоняют основной сервер
+func NewCachedInventory(inventory interfaces.InventoryRepo, cache interfaces.CacheRepo, catalogTTL, replicaLag time.Duration, metrics interfaces.MetricsRepo, logger interfaces.LoggerRepo) *CachedInventory {
+	return &CachedInventory{
+		InventoryRepo: inventory,
+		catalog:       cacheSpace{name: CACHE_CATALOG, cache: cache, ttl: catalogTTL, metrics: metrics, logger: logger},
+		userInfo:      cacheSpace{name: CACHE_USER_INFO, cache: cache, hold: replicaLag, metrics: metrics, logger: logger},
+	}
+}
+
+// GetSubjectByName получает предмет по его названию из кэша или из базы данных
+func (s *CachedInventory) GetSubjectByName(ctx context.Context, name string) (*domain.Item, error) {
+	return fetch(ctx, s.catalog, name, func() (*domain.Item, error) {
+		return s.InventoryRepo.GetSubjectByName(ctx, name)
+	})
+}
+
+// Buy выполняет покупку предмета и сбрасывает сводку пользователя
+func (s *CachedInventory) Buy(ctx context.Context, userId domain.UserId, subject domain.Item) error {
+	err := s.InventoryRepo.Buy(ctx, userId, subject)
+	if err != nil {
+		return err
+	}
+
+	s.userInfo.invalidate(ctx, userInfoId(userId))
+	return nil
+}
+
+// CachedTransaction сбрасывает сводки отправителя и получателя после перевода
+type CachedTransaction struct {
+	interfaces.TransactionRepo
+	users    interfaces.UserRepo
+	userInfo cacheSpace
+}
+
+// NewCachedTransaction создает новый экземпляр CachedTransaction поверх transaction.
+// users нужен, чтобы найти идентификаторы участников перевода по email, replicaLag - как в NewCachedInventory
+func NewCachedTransaction(transaction interfaces.TransactionRepo, users interfaces.UserRepo, cache interfaces.CacheRepo, replicaLag time.Duration, metrics interfaces.MetricsRepo, logger interfaces.LoggerRepo) *CachedTransaction {
+	return &CachedTransaction{
+		TransactionRepo: transaction,
+		users:           users,
+		userInfo:        cacheSpace{name: CACHE_USER_INFO, cache: cache, hold: replicaLag, metrics: metrics, logger: logger},
+	}
+}
+
+// Transfer выполняет перевод и сбрасывает сводки отправителя и получателя
+func (s *CachedTransaction) Transfer(ctx context.Context, transaction domain.Transaction) error {
+	err := s.TransactionRepo.Transfer(ctx, transaction)
+	if err != nil {
+		return err
+	}
+
+	invalidateUsers(ctx, s.users, s.userInfo, transaction.SenderName, transaction.ReceiverName)
+	return nil
+}
+
+// CachedUser сбрасывает сводку пользователя после начисления монет.
+// Остальные методы UserRepo выполняются без кэша
+type CachedUser struct {
+	interfaces.UserRepo
+	userInfo cacheSpace
+}
+
+// NewCachedUser создает новый экземпляр CachedUser поверх user, replicaLag - как в NewCachedInventory
+func NewCachedUser(user interfaces.UserRepo, cache interfaces.CacheRepo, replicaLag time.Duration, metrics interfaces.MetricsRepo, logger interfaces.LoggerRepo) *CachedUser {
+	return &CachedUser{
+		UserRepo: user,
+		userInfo: cacheSpace{name: CACHE_USER_INFO, cache: cache, hold: replicaLag, metrics: metrics, logger: logger},
+	}
+}
+
+// AddCoins начисляет монеты пользователю и сбрасывает его сводку
+func (s *CachedUser) AddCoins(ctx context.Context, grant domain.Grant) error {
+	err := s.UserRepo.AddCoins(ctx, grant)
+	if err != nil {
+		return err
+	}
+
+	invalidateUsers(ctx, s.UserRepo, s.userInfo, grant.ReceiverName)
+	return nil
+}
+
+// CachedUserInfo кэширует сводку пользователя для /api/info
+type CachedUserInfo struct {
+	info     interfaces.UserInfoRepo
+	userInfo cacheSpace
+}
+
+// NewCachedUserInfo создает новый экземпляр CachedUserInfo поверх info.
+// Сводка хранится в cache ttl, 0 выключает кэширование
+func NewCachedUserInfo(info interfaces.UserInfoRepo, cache interfaces.CacheRepo, ttl time.Duration, metrics interfaces.MetricsRepo, logger interfaces.LoggerRepo) *CachedUserInfo {
+	return &CachedUserInfo{
+		info:     info,
+		userInfo: cacheSpace{name: CACHE_USER_INFO, cache: cache, ttl: ttl, metrics: metrics, logger: logger},
+	}
+}
+
+// GetInfo получает сводку пользователя из кэша или из базы данных.
+// Запрос, направленный на основной сервер через postgres.WithPrimary, должен увидеть последние записи,
+// поэтому выполняется без кэша
+func (s *CachedUserInfo) GetInfo(ctx context.Context, userId domain.UserId) (*domain.UserInfo, error) {
+	if postgres.UsesPrimary(ctx) {
+		return s.info.GetInfo(ctx, userId)
+	}
+
+	return fetch(ctx, s.userInfo, userInfoId(userId), func() (*domain.UserInfo, error) {
+		return s.info.GetInfo(ctx, userId)
+	})
+}
@@ -0,0 +1,169 @@
+package realization
+
+import (
+	"context"
+	"errors"
+	"merch/internal/domain"
+	"merch/internal/presentation/postgres"
+	"merch/test/mocks"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newCacheMocks() (*mocks.MockMetricsRepo, *mocks.LoggerRepo) {
+	metrics := new(mocks.MockMetricsRepo)
+	metrics.On("CacheHit", mock.Anything).Maybe()
+	metrics.On("CacheMiss", mock.Anything).Maybe()
+
+	logger := new(mocks.LoggerRepo)
+	logger.On("Warn", mock.Anything, mock.Anything).Maybe()
+	return metrics, logger
+}
+
+func TestCachedInventory_GetSubjectByName(t *testing.T) {
+	ctx := context.Background()
+	metrics, logger := newCacheMocks()
+	inventory := new(mocks.MockInventoryRepo)
+	cached := NewCachedInventory(inventory, NewMemoryCache(), time.Minute, 0, metrics, logger)
+
+	cup := &domain.Item{Id: 2, Name: "cup", Cost: 20}
+	inventory.On("GetSubjectByName", ctx, "cup").Return(cup, nil).Once()
+	inventory.On("GetSubjectByName", ctx, "car").Return((*domain.Item)(nil), errors.New("subject not exists")).Twice()
+
+	// Второй запрос предмета читается из кэша
+	for range 2 {
+		item, err := cached.GetSubjectByName(ctx, "cup")
+		require.NoError(t, err)
+		assert.Equal(t, cup, item)
+	}
+
+	// Ошибки не кэшируются
+	for range 2 {
+		_, err := cached.GetSubjectByName(ctx, "car")
+		assert.Error(t, err)
+	}
+
+	inventory.AssertExpectations(t)
+	metrics.AssertNumberOfCalls(t, "CacheHit", 1)
+	metrics.AssertNumberOfCalls(t, "CacheMiss", 3)
+}
+
+func TestCachedUserInfo_Invalidation(t *testing.T) {
+	ctx := context.Background()
+	metrics, logger := newCacheMocks()
+	cache := NewMemoryCache()
+
+	info := new(mocks.MockUserInfoRepo)
+	users := new(mocks.MockUserRepo)
+	inventory := new(mocks.MockInventoryRepo)
+	transactions := new(mocks.MockTransactionRepo)
+
+	cachedInfo := NewCachedUserInfo(info, cache, time.Minute, metrics, logger)
+	cachedInventory := NewCachedInventory(inventory, cache, time.Minute, 0, metrics, logger)
+	cachedTransactions := NewCachedTransaction(transactions, users, cache, 0, metrics, logger)
+	cachedUsers := NewCachedUser(users, cache, 0, metrics, logger)
+
+	users.On("GetByEmail", ctx, "user@example.com").Return(&domain.User{Id: 1, Email: "user@example.com"}, nil)
+	users.On("GetByEmail", ctx, "peer@example.com").Return(&domain.User{Id: 2, Email: "peer@example.com"}, nil)
+	info.On("GetInfo", ctx, uint64(1)).Return(&domain.UserInfo{Coins: 1000}, nil)
+	info.On("GetInfo", ctx, uint64(2)).Return(&domain.UserInfo{Coins: 500}, nil)
+
+	// loads кэширует сводки обоих пользователей и возвращает число обращений к базе данных
+	loads := func() int {
+		for _, id := range []uint64{1, 2} {
+			_, err := cachedInfo.GetInfo(ctx, id)
+			require.NoError(t, err)
+		}
+		return len(info.Calls)
+	}
+	assert.Equal(t, 2, loads())
+	assert.Equal(t, 2, loads(), "cached info is not loaded again")
+
+	item := domain.Item{Name: "cup", Cost: 20}
+	inventory.On("Buy", ctx, uint64(1), item).Return(nil).Once()
+	require.NoError(t, cachedInventory.Buy(ctx, 1, item))
+	assert.Equal(t, 3, loads(), "purchase invalidates the buyer")
+
+	transfer := domain.Transaction{SenderName: "user@example.com", ReceiverName: "peer@example.com", Amount: 10}
+	transactions.On("Transfer", ctx, transfer).Return(nil).Once()
+	require.NoError(t, cachedTransactions.Transfer(ctx, transfer))
+	assert.Equal(t, 5, loads(), "transfer invalidates the sender and the receiver")
+
+	grant := domain.Grant{ApiKeyId: 1, ReceiverName: "peer@example.com", Amount: 100}
+	users.On("AddCoins", ctx, grant).Return(nil).Once()
+	require.NoError(t, cachedUsers.AddCoins(ctx, grant))
+	assert.Equal(t, 6, loads(), "grant invalidates the receiver")
+
+	// Неудачная запись ничего не сбрасывает
+	inventory.On("Buy", ctx, uint64(1), item).Return(errors.New("db error")).Once()
+	assert.Error(t, cachedInventory.Buy(ctx, 1, item))
+	assert.Equal(t, 6, loads())
+}
+
+func TestCachedUserInfo_Disabled(t *testing.T) {
+	ctx := context.Background()
+	metrics, logger := newCacheMocks()
+	info := new(mocks.MockUserInfoRepo)
+	cached := NewCachedUserInfo(info, NewMemoryCache(), 0, metrics, logger)
+
+	info.On("GetInfo", ctx, uint64(1)).Return(&domain.UserInfo{Coins: 1000}, nil).Twice()
+	for range 2 {
+		_, err := cached.GetInfo(ctx, 1)
+		require.NoError(t, err)
+	}
+
+	info.AssertExpectations(t)
+	metrics.AssertNotCalled(t, "CacheMiss", mock.Anything)
+}
+
+func TestCachedUserInfo_ReplicaLag(t *testing.T) {
+	ctx := context.Background()
+	metrics, logger := newCacheMocks()
+	now := time.Now()
+	cache := NewMemoryCache()
+	cache.now = func() time.Time { return now }
+
+	info := new(mocks.MockUserInfoRepo)
+	users := new(mocks.MockUserRepo)
+	transactions := new(mocks.MockTransactionRepo)
+	cachedInfo := NewCachedUserInfo(info, cache, time.Minute, metrics, logger)
+	cachedTransactions := NewCachedTransaction(transactions, users, cache, 5*time.Second, metrics, logger)
+
+	users.On("GetByEmail", ctx, "user@example.com").Return(&domain.User{Id: 1, Email: "user@example.com"}, nil)
+	users.On("GetByEmail", ctx, "peer@example.com").Return(&domain.User{Id: 2, Email: "peer@example.com"}, nil)
+	getInfo := func(ctx context.Context) int {
+		userInfo, err := cachedInfo.GetInfo(ctx, 2)
+		require.NoError(t, err)
+		return userInfo.Coins
+	}
+
+	info.On("GetInfo", mock.Anything, uint64(2)).Return(&domain.UserInfo{Coins: 500}, nil).Once()
+	assert.Equal(t, 500, getInfo(ctx))
+
+	transfer := domain.Transaction{SenderName: "user@example.com", ReceiverName: "peer@example.com", Amount: 10}
+	transactions.On("Transfer", ctx, transfer).Return(nil).Once()
+	require.NoError(t, cachedTransactions.Transfer(ctx, transfer))
+
+	// Реплика еще не получила перевод: старая сводка отдается, но не кэшируется
+	info.On("GetInfo", mock.Anything, uint64(2)).Return(&domain.UserInfo{Coins: 500}, nil).Once()
+	assert.Equal(t, 500, getInfo(ctx))
+	info.On("GetInfo", mock.Anything, uint64(2)).Return(&domain.UserInfo{Coins: 510}, nil).Once()
+	assert.Equal(t, 510, getInfo(ctx))
+
+	// Запрос на основной сервер выполняется без кэша
+	info.On("GetInfo", mock.Anything, uint64(2)).Return(&domain.UserInfo{Coins: 510}, nil).Once()
+	assert.Equal(t, 510, getInfo(postgres.WithPrimary(ctx)))
+
+	// После задержки реплик сводка снова кэшируется
+	now = now.Add(5 * time.Second)
+	info.On("GetInfo", mock.Anything, uint64(2)).Return(&domain.UserInfo{Coins: 510}, nil).Once()
+	assert.Equal(t, 510, getInfo(ctx))
+	assert.Equal(t, 510, getInfo(ctx))
+
+	info.AssertExpectations(t)
+	assert.Len(t, info.Calls, 5)
+}
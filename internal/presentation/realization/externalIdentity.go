@@ -0,0 +1,59 @@
+package realization
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"merch/internal/domain"
+	"merch/internal/interfaces"
+	"time"
+)
+
+// ExternalIdentity - структура для работы с внешними учетными записями
+type ExternalIdentity struct {
+	db      *sql.DB
+	logger  interfaces.LoggerRepo
+	timeout time.Duration
+}
+
+// NewExternalIdentity создает новый экземпляр ExternalIdentity с подключением к базе данных и временем выполнения одного запроса
+func NewExternalIdentity(db *sql.DB, logger interfaces.LoggerRepo, timeout time.Duration) *ExternalIdentity {
+	return &ExternalIdentity{
+		db:      db,
+		logger:  logger,
+		timeout: timeout,
+	}
+}
+
+// GetUserId получает идентификатор пользователя, связанного с внешней учетной записью
+func (s *ExternalIdentity) GetUserId(ctx context.Context, provider, subject string) (*domain.UserId, error) {
+	s.logger.Debug("Getting external identity")
+	var id domain.UserId
+	ctx, cancel := queryContext(ctx, s.timeout)
+	defer cancel()
+	err := s.db.QueryRowContext(ctx, `SELECT "user_id" FROM ExternalIdentity WHERE "provider" = $1 AND "subject" = $2`, provider, subject).Scan(&id)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, createDbQueryError(err)
+	}
+
+	return &id, nil
+}
+
+// Link связывает внешнюю учетную запись с пользователем
+func (s *ExternalIdentity) Link(ctx context.Context, provider, subject string, userId domain.UserId) error {
+	s.logger.Debug("Linking external identity")
+	ctx, cancel := queryContext(ctx, s.timeout)
+	defer cancel()
+	_, err := s.db.ExecContext(ctx, `INSERT INTO ExternalIdentity ("provider", "subject", "user_id") VALUES ($1, $2, $3)`, provider, subject, userId)
+
+	if err != nil {
+		return createDbQueryError(err)
+	}
+
+	return nil
+}
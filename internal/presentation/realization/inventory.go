@@ -4,67 +4,80 @@ import (
 	"context"
 	"database/sql"
 	"errors"
-	"fmt"
 	"merch/internal/domain"
+	"merch/internal/interfaces"
 	e "merch/internal/presentation/customError"
-	"merch/internal/presentation/postgres"
-	"net/http"
 	"time"
 )
 
 // Inventory представляет собой структуру для работы с инвентарем
-type Inventory struct{}
+type Inventory struct {
+	db      *sql.DB
+	read    ReadRouter
+	logger  interfaces.LoggerRepo
+	timeout time.Duration
+}
 
-// NewInventory создает новый экземпляр Inventory
-func NewInventory() *Inventory {
-	return &Inventory{}
+// NewInventory создает новый экземпляр Inventory с подключением к базе данных, выбором подключения для чтения и временем выполнения одного запроса.
+// Если read равен nil, запросы на чтение тоже выполняются через db
+func NewInventory(db *sql.DB, read ReadRouter, logger interfaces.LoggerRepo, timeout time.Duration) *Inventory {
+	return &Inventory{
+		db:      db,
+		read:    read,
+		logger:  logger,
+		timeout: timeout,
+	}
 }
 
 // Buy выполняет покупку предмета
-func (s *Inventory) Buy(userId domain.UserId, subject domain.Item) error {
-	LoggerService.Debug("Buying subject")
-	tx, err := postgres.DbService.Db.Begin()
+func (s *Inventory) Buy(ctx context.Context, userId domain.UserId, subject domain.Item) error {
+	s.logger.Debug("Buying subject")
+	tx, err := s.db.BeginTx(ctx, nil)
 
 	if err != nil {
 		return &e.TransactionError{
-			Code: http.StatusInternalServerError,
-			Err:  fmt.Sprintf("Creating transaction error: %v", err),
+			Kind:  domain.ErrInternal,
+			Err:   "Creating transaction error",
+			Cause: err,
 		}
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	updateCtx, cancel := queryContext(ctx, s.timeout)
 	defer cancel()
-	_, err = tx.ExecContext(ctx, `UPDATE Users SET "coins" = "coins" - $1 WHERE "id" = $2`, subject.Cost, userId)
+	_, err = tx.ExecContext(updateCtx, `UPDATE Users SET "coins" = "coins" - $1 WHERE "id" = $2`, subject.Cost, userId)
 	if err != nil {
 		err = tx.Rollback()
 		if err != nil {
-			LoggerService.Error(fmt.Sprintf("Rollback error: %v", err))
+			s.logger.Error("Rollback error", domain.Field("error", err.Error()))
 		}
 		return &e.DbQueryError{
-			Code: http.StatusInternalServerError,
-			Err:  fmt.Sprintf("Db query error: %v", err),
+			Kind:  domain.ErrInternal,
+			Err:   "Db query error",
+			Cause: err,
 		}
 	}
 
-	ctx, cancel = context.WithTimeout(context.Background(), time.Second*3)
+	insertCtx, cancel := queryContext(ctx, s.timeout)
 	defer cancel()
-	_, err = tx.ExecContext(ctx, `INSERT INTO Inventory ("subject_name", "user_id") VALUES ($1, $2)`, subject.Name, userId)
+	_, err = tx.ExecContext(insertCtx, `INSERT INTO Inventory ("subject_name", "user_id") VALUES ($1, $2)`, subject.Name, userId)
 	if err != nil {
 		err = tx.Rollback()
 		if err != nil {
-			LoggerService.Error(fmt.Sprintf("Rollback error: %v", err))
+			s.logger.Error("Rollback error", domain.Field("error", err.Error()))
 		}
 		return &e.DbQueryError{
-			Code: http.StatusInternalServerError,
-			Err:  fmt.Sprintf("Db query error: %v", err),
+			Kind:  domain.ErrInternal,
+			Err:   "Db query error",
+			Cause: err,
 		}
 	}
 
 	err = tx.Commit()
 	if err != nil {
 		return &e.TransactionError{
-			Code: http.StatusInternalServerError,
-			Err:  fmt.Sprintf("Commit error: %v", err),
+			Kind:  domain.ErrInternal,
+			Err:   "Commit error",
+			Cause: err,
 		}
 	}
 
@@ -72,24 +85,25 @@ func (s *Inventory) Buy(userId domain.UserId, subject domain.Item) error {
 }
 
 // GetSubjectByName получает предмет по его названию
-func (s *Inventory) GetSubjectByName(name domain.UserEmail) (*domain.Item, error) {
-	LoggerService.Debug("Getting subject")
+func (s *Inventory) GetSubjectByName(ctx context.Context, name domain.UserEmail) (*domain.Item, error) {
+	s.logger.Debug("Getting subject")
 	var subject domain.Item
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	ctx, cancel := queryContext(ctx, s.timeout)
 	defer cancel()
-	err := postgres.DbService.Db.QueryRowContext(ctx, `SELECT "id", "name", "cost" FROM Subject WHERE "name" = $1`, name).Scan(&subject.Id, &subject.Name, &subject.Cost)
+	err := s.db.QueryRowContext(ctx, `SELECT "id", "name", "cost" FROM Subject WHERE "name" = $1`, name).Scan(&subject.Id, &subject.Name, &subject.Cost)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, &e.RowsNotFoundError{
-				Code: http.StatusBadRequest,
+				Kind: domain.ErrInvalidInput,
 				Err:  "Subject not exists",
 			}
 		}
 
 		return nil, &e.DbQueryError{
-			Code: http.StatusInternalServerError,
-			Err:  fmt.Sprintf("Query db error: %v", err),
+			Kind:  domain.ErrInternal,
+			Err:   "Query db error",
+			Cause: err,
 		}
 	}
 
@@ -97,23 +111,24 @@ func (s *Inventory) GetSubjectByName(name domain.UserEmail) (*domain.Item, error
 }
 
 // GetInventory получает инвентарь пользователя по его идентификатору
-func (s *Inventory) GetInventory(userId domain.UserId) (*[]domain.Inventory, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+func (s *Inventory) GetInventory(ctx context.Context, userId domain.UserId) (*[]domain.Inventory, error) {
+	ctx, cancel := queryContext(ctx, s.timeout)
 	defer cancel()
-	rows, err := postgres.DbService.Db.QueryContext(ctx, `SELECT "id", "subject_name", "user_id" FROM Inventory WHERE "user_id" = $1`, userId)
+	rows, err := reader(ctx, s.db, s.read).QueryContext(ctx, `SELECT "id", "subject_name", "user_id" FROM Inventory WHERE "user_id" = $1`, userId)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
 		}
 		return nil, &e.DbQueryError{
-			Code: http.StatusInternalServerError,
-			Err:  fmt.Sprintf("Query db error: %v", err),
+			Kind:  domain.ErrInternal,
+			Err:   "Query db error",
+			Cause: err,
 		}
 	}
 	defer func() {
 		err := rows.Close()
 		if err != nil {
-			LoggerService.Error(fmt.Sprintf("Error closing rows: %v", err))
+			s.logger.Error("Error closing rows", domain.Field("error", err.Error()))
 		}
 	}()
 
@@ -123,8 +138,9 @@ func (s *Inventory) GetInventory(userId domain.UserId) (*[]domain.Inventory, err
 		err = rows.Scan(&subject.Id, &subject.Subject, &subject.UserId)
 		if err != nil {
 			return nil, &e.DbQueryError{
-				Code: http.StatusInternalServerError,
-				Err:  fmt.Sprintf("Scanning subject error: %v", err),
+				Kind:  domain.ErrInternal,
+				Err:   "Scanning subject error",
+				Cause: err,
 			}
 		}
 		inventory = append(inventory, subject)
@@ -2,15 +2,12 @@ package realization
 
 import (
 	"fmt"
+	"merch/internal/domain"
 	"merch/internal/interfaces"
 	e "merch/internal/presentation/customError"
-	"net/http"
 
 	"go.uber.org/zap"
-)
-
-var (
-	LoggerService interfaces.LoggerRepo
+	"go.uber.org/zap/zapcore"
 )
 
 // Logger - структура для логгирования с использованием zap
@@ -18,42 +15,89 @@ type Logger struct {
 	logger *zap.Logger
 }
 
-// NewLogger создает новый экземпляр логгера с заданным путем для вывода логов
-func NewLogger() (interfaces.LoggerRepo, error) {
-	config := zap.NewDevelopmentConfig()
+// NewLogger создает новый экземпляр логгера с выводом в stdout.
+// level - минимальный уровень записей (debug, info, warn, error), format - domain.LOG_FORMAT_JSON или domain.LOG_FORMAT_CONSOLE
+func NewLogger(level, format string) (interfaces.LoggerRepo, error) {
+	var config zap.Config
+	switch format {
+	case domain.LOG_FORMAT_JSON:
+		config = zap.NewProductionConfig()
+	case domain.LOG_FORMAT_CONSOLE:
+		config = zap.NewDevelopmentConfig()
+	default:
+		return nil, loggerError(fmt.Errorf("unknown format %q", format))
+	}
+
+	atomicLevel, err := zap.ParseAtomicLevel(level)
+	if err != nil {
+		return nil, loggerError(err)
+	}
+
+	config.Level = atomicLevel
 	config.OutputPaths = []string{"stdout"}
+	config.EncoderConfig.TimeKey = "time"
+	config.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
 
 	logger, err := config.Build()
 	if err != nil {
-		return nil, &e.LoggerBuildError{
-			Code: http.StatusInternalServerError,
-			Err:  fmt.Sprintf("Failed to configure logger: %v", err),
-		}
+		return nil, loggerError(err)
 	}
 
-	LoggerService = &Logger{
+	return &Logger{
+		logger: logger,
+	}, nil
+}
+
+// NewZapLogger создает логгер поверх готового zap.Logger, например для тестов
+func NewZapLogger(logger *zap.Logger) interfaces.LoggerRepo {
+	return &Logger{
 		logger: logger,
 	}
+}
 
-	return LoggerService, nil
+func (l *Logger) Fatal(msg string, fields ...domain.LogField) {
+	l.logger.Fatal(msg, zapFields(fields)...)
 }
 
-func (l *Logger) Fatal(msg string) {
-	l.logger.Fatal(msg)
+func (l *Logger) Error(msg string, fields ...domain.LogField) {
+	l.logger.Error(msg, zapFields(fields)...)
 }
 
-func (l *Logger) Error(msg string) {
-	l.logger.Error(msg)
+func (l *Logger) Warn(msg string, fields ...domain.LogField) {
+	l.logger.Warn(msg, zapFields(fields)...)
 }
 
-func (l *Logger) Warn(msg string) {
-	l.logger.Warn(msg)
+func (l *Logger) Info(msg string, fields ...domain.LogField) {
+	l.logger.Info(msg, zapFields(fields)...)
 }
 
-func (l *Logger) Info(msg string) {
-	l.logger.Info(msg)
+func (l *Logger) Debug(msg string, fields ...domain.LogField) {
+	l.logger.Debug(msg, zapFields(fields)...)
 }
 
-func (l *Logger) Debug(msg string) {
-	l.logger.Debug(msg)
+func (l *Logger) With(fields ...domain.LogField) interfaces.LoggerRepo {
+	return &Logger{
+		logger: l.logger.With(zapFields(fields)...),
+	}
+}
+
+func zapFields(fields []domain.LogField) []zap.Field {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	result := make([]zap.Field, len(fields))
+	for i, field := range fields {
+		result[i] = zap.Any(field.Key, field.Value)
+	}
+
+	return result
+}
+
+func loggerError(err error) error {
+	return &e.LoggerBuildError{
+		Kind:  domain.ErrInternal,
+		Err:   "Failed to configure logger",
+		Cause: err,
+	}
 }
@@ -0,0 +1,42 @@
+package realization
+
+import (
+	"merch/internal/domain"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestNewLogger(t *testing.T) {
+	for _, format := range []string{domain.LOG_FORMAT_JSON, domain.LOG_FORMAT_CONSOLE} {
+		logger, err := NewLogger("debug", format)
+		require.NoError(t, err, format)
+		assert.NotNil(t, logger)
+	}
+
+	_, err := NewLogger("loud", domain.LOG_FORMAT_JSON)
+	assert.Error(t, err)
+
+	_, err = NewLogger("info", "xml")
+	assert.Error(t, err)
+}
+
+func TestLogger_With(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	logger := NewZapLogger(zap.New(core))
+
+	child := logger.With(domain.Field("requestId", "abc"))
+	child.Info("Coins sent", domain.Field("amount", 10))
+	child.Debug("Filtered by level")
+	logger.Warn("Without request")
+
+	entries := logs.All()
+	require.Len(t, entries, 2)
+
+	assert.Equal(t, map[string]any{"requestId": "abc", "amount": int64(10)}, entries[0].ContextMap())
+	assert.Empty(t, entries[1].ContextMap())
+}
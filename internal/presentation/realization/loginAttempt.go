@@ -0,0 +1,112 @@
+package realization
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"merch/internal/domain"
+	"merch/internal/interfaces"
+	e "merch/internal/presentation/customError"
+	"time"
+)
+
+// LoginAttempt - структура для хранения счетчиков неудачных попыток входа в PostgreSQL
+type LoginAttempt struct {
+	db      *sql.DB
+	logger  interfaces.LoggerRepo
+	timeout time.Duration
+}
+
+// NewLoginAttempt создает новый экземпляр LoginAttempt с подключением к базе данных и временем выполнения одного запроса
+func NewLoginAttempt(db *sql.DB, logger interfaces.LoggerRepo, timeout time.Duration) *LoginAttempt {
+	return &LoginAttempt{
+		db:      db,
+		logger:  logger,
+		timeout: timeout,
+	}
+}
+
+// Get возвращает состояние счетчика по ключу
+func (s *LoginAttempt) Get(ctx context.Context, key string) (*domain.LoginAttempt, error) {
+	s.logger.Debug("Getting login attempts")
+	attempt := domain.LoginAttempt{Key: key}
+	var retryAfter float64
+	ctx, cancel := queryContext(ctx, s.timeout)
+	defer cancel()
+	err := s.db.QueryRowContext(ctx, `SELECT "failures", GREATEST(EXTRACT(EPOCH FROM ("locked_until" - NOW())), 0) FROM LoginAttempt WHERE "login_key" = $1`, key).Scan(&attempt.Failures, &retryAfter)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, &e.DbQueryError{
+			Kind:  domain.ErrInternal,
+			Err:   "Query db error",
+			Cause: err,
+		}
+	}
+
+	attempt.RetryAfter = time.Duration(retryAfter * float64(time.Second))
+	return &attempt, nil
+}
+
+// Fail увеличивает счетчик неудачных попыток. Если последняя неудача была раньше window, счетчик начинается заново
+func (s *LoginAttempt) Fail(ctx context.Context, key string, window time.Duration) (int, error) {
+	s.logger.Debug("Registering failed login attempt")
+	var failures int
+	ctx, cancel := queryContext(ctx, s.timeout)
+	defer cancel()
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO LoginAttempt ("login_key", "failures", "last_failure") VALUES ($1, 1, NOW())
+		ON CONFLICT ("login_key") DO UPDATE SET
+			"failures" = CASE WHEN LoginAttempt."last_failure" < NOW() - make_interval(secs => $2) THEN 1 ELSE LoginAttempt."failures" + 1 END,
+			"last_failure" = NOW()
+		RETURNING "failures"`, key, window.Seconds()).Scan(&failures)
+
+	if err != nil {
+		return 0, &e.DbQueryError{
+			Kind:  domain.ErrInternal,
+			Err:   "Query db error",
+			Cause: err,
+		}
+	}
+
+	return failures, nil
+}
+
+// Lock блокирует вход по ключу на указанное время
+func (s *LoginAttempt) Lock(ctx context.Context, key string, duration time.Duration) error {
+	s.logger.Debug("Locking login")
+	ctx, cancel := queryContext(ctx, s.timeout)
+	defer cancel()
+	_, err := s.db.ExecContext(ctx, `UPDATE LoginAttempt SET "locked_until" = NOW() + make_interval(secs => $2) WHERE "login_key" = $1`, key, duration.Seconds())
+
+	if err != nil {
+		return &e.DbQueryError{
+			Kind:  domain.ErrInternal,
+			Err:   "Query db error",
+			Cause: err,
+		}
+	}
+
+	return nil
+}
+
+// Reset сбрасывает счетчик неудачных попыток
+func (s *LoginAttempt) Reset(ctx context.Context, key string) error {
+	s.logger.Debug("Resetting login attempts")
+	ctx, cancel := queryContext(ctx, s.timeout)
+	defer cancel()
+	_, err := s.db.ExecContext(ctx, `DELETE FROM LoginAttempt WHERE "login_key" = $1`, key)
+
+	if err != nil {
+		return &e.DbQueryError{
+			Kind:  domain.ErrInternal,
+			Err:   "Query db error",
+			Cause: err,
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,121 @@
+package realization
+
+import (
+	"database/sql"
+	"merch/internal/interfaces"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// METRICS_NAMESPACE - префикс имен всех метрик сервиса
+const METRICS_NAMESPACE = "merch"
+
+// Metrics - метрики сервиса в формате Prometheus
+type Metrics struct {
+	registry  *prometheus.Registry
+	requests  *prometheus.CounterVec
+	latency   *prometheus.HistogramVec
+	coins     prometheus.Counter
+	purchases *prometheus.CounterVec
+	logins    *prometheus.CounterVec
+	cache     *prometheus.CounterVec
+}
+
+// NewMetrics создает новый экземпляр Metrics со своим реестром.
+// В метрики попадает статистика пулов соединений с базой данных pools с меткой host - адресом сервера
+func NewMetrics(pools map[string]*sql.DB) interfaces.MetricsRepo {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: METRICS_NAMESPACE,
+			Name:      "http_requests_total",
+			Help:      "Number of handled HTTP requests.",
+		}, []string{"method", "route", "status"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: METRICS_NAMESPACE,
+			Name:      "http_request_duration_seconds",
+			Help:      "Latency of handled HTTP requests.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		coins: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: METRICS_NAMESPACE,
+			Name:      "coins_transferred_total",
+			Help:      "Number of coins transferred between users.",
+		}),
+		purchases: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: METRICS_NAMESPACE,
+			Name:      "purchases_total",
+			Help:      "Number of purchased items.",
+		}, []string{"item"}),
+		logins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: METRICS_NAMESPACE,
+			Name:      "failed_logins_total",
+			Help:      "Number of failed login attempts.",
+		}, []string{"reason"}),
+		cache: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: METRICS_NAMESPACE,
+			Name:      "cache_requests_total",
+			Help:      "Number of cache lookups by result: hit or miss.",
+		}, []string{"cache", "result"}),
+	}
+
+	m.registry.MustRegister(
+		m.requests,
+		m.latency,
+		m.coins,
+		m.purchases,
+		m.logins,
+		m.cache,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	for host, db := range pools {
+		registry := prometheus.WrapRegistererWith(prometheus.Labels{"host": host}, m.registry)
+		registry.MustRegister(collectors.NewDBStatsCollector(db, METRICS_NAMESPACE))
+	}
+
+	return m
+}
+
+// ObserveRequest учитывает обработанный запрос и его длительность
+func (m *Metrics) ObserveRequest(method, route string, status int, duration time.Duration) {
+	code := strconv.Itoa(status)
+	m.requests.WithLabelValues(method, route, code).Inc()
+	m.latency.WithLabelValues(method, route, code).Observe(duration.Seconds())
+}
+
+// CoinsTransferred добавляет переведенные монеты к общей сумме
+func (m *Metrics) CoinsTransferred(amount int) {
+	m.coins.Add(float64(amount))
+}
+
+// ItemPurchased учитывает покупку предмета
+func (m *Metrics) ItemPurchased(item string) {
+	m.purchases.WithLabelValues(item).Inc()
+}
+
+// LoginFailed учитывает неудачный вход с причиной reason
+func (m *Metrics) LoginFailed(reason string) {
+	m.logins.WithLabelValues(reason).Inc()
+}
+
+// CacheHit учитывает попадание в кэш
+func (m *Metrics) CacheHit(cache string) {
+	m.cache.WithLabelValues(cache, "hit").Inc()
+}
+
+// CacheMiss учитывает промах кэша
+func (m *Metrics) CacheMiss(cache string) {
+	m.cache.WithLabelValues(cache, "miss").Inc()
+}
+
+// Handler возвращает обработчик, который отдает метрики в формате Prometheus
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
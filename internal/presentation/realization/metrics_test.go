@@ -0,0 +1,47 @@
+package realization
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics_Handler(t *testing.T) {
+	primary, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer primary.Close()
+	replica, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer replica.Close()
+
+	metrics := NewMetrics(map[string]*sql.DB{"primary:5432": primary, "replica:5432": replica})
+	metrics.ObserveRequest(http.MethodGet, "/api/buy/:item", http.StatusOK, 20*time.Millisecond)
+	metrics.CoinsTransferred(10)
+	metrics.CoinsTransferred(5)
+	metrics.ItemPurchased("cup")
+	metrics.LoginFailed("password")
+	metrics.CacheHit("catalog")
+	metrics.CacheMiss("catalog")
+	metrics.CacheHit("catalog")
+
+	w := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	body := w.Body.String()
+	assert.Contains(t, body, `merch_http_requests_total{method="GET",route="/api/buy/:item",status="200"} 1`)
+	assert.Contains(t, body, `merch_http_request_duration_seconds_bucket{method="GET",route="/api/buy/:item",status="200",le="0.025"} 1`)
+	assert.Contains(t, body, "merch_coins_transferred_total 15")
+	assert.Contains(t, body, `merch_purchases_total{item="cup"} 1`)
+	assert.Contains(t, body, `merch_failed_logins_total{reason="password"} 1`)
+	assert.Contains(t, body, `merch_cache_requests_total{cache="catalog",result="hit"} 2`)
+	assert.Contains(t, body, `merch_cache_requests_total{cache="catalog",result="miss"} 1`)
+	assert.Contains(t, body, `go_sql_open_connections{db_name="merch",host="primary:5432"}`)
+	assert.Contains(t, body, `go_sql_open_connections{db_name="merch",host="replica:5432"}`)
+}
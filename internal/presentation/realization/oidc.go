@@ -0,0 +1,128 @@
+package realization
+
+import (
+	"context"
+	"merch/internal/domain"
+	"merch/internal/interfaces"
+	e "merch/internal/presentation/customError"
+	"net/http"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// OIDC - вход через провайдера OpenID Connect по схеме authorization code
+type OIDC struct {
+	name     string
+	config   oauth2.Config
+	verifier *oidc.IDTokenVerifier
+	client   *http.Client
+	logger   interfaces.LoggerRepo
+}
+
+// oidcClaims - данные пользователя из ID-токена
+type oidcClaims struct {
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+}
+
+// NewOIDC создает новый экземпляр OIDC. Адреса провайдера получаются из документа discovery издателя
+func NewOIDC(name, issuer, clientId, clientSecret, redirectURL string, client *http.Client, logger interfaces.LoggerRepo) (*OIDC, error) {
+	if client == nil {
+		client = &http.Client{Timeout: time.Second * 10}
+	}
+
+	ctx, cancel := context.WithTimeout(oidc.ClientContext(context.Background(), client), time.Second*10)
+	defer cancel()
+
+	provider, err := oidc.NewProvider(ctx, issuer)
+	if err != nil {
+		return nil, &e.IdentityProviderError{
+			Kind:  domain.ErrInternal,
+			Err:   "OIDC discovery error",
+			Cause: err,
+		}
+	}
+
+	return &OIDC{
+		name: name,
+		config: oauth2.Config{
+			ClientID:     clientId,
+			ClientSecret: clientSecret,
+			RedirectURL:  redirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       []string{oidc.ScopeOpenID, "email", "profile"},
+		},
+		verifier: provider.VerifierContext(oidc.ClientContext(context.Background(), client), &oidc.Config{ClientID: clientId}),
+		client:   client,
+		logger:   logger,
+	}, nil
+}
+
+// Name возвращает название провайдера
+func (s *OIDC) Name() string {
+	return s.name
+}
+
+// AuthCodeURL возвращает адрес страницы входа провайдера
+func (s *OIDC) AuthCodeURL(state, nonce string) string {
+	return s.config.AuthCodeURL(state, oidc.Nonce(nonce))
+}
+
+// Exchange обменивает код авторизации на токены и проверяет подпись, издателя, получателя и nonce ID-токена
+func (s *OIDC) Exchange(ctx context.Context, code, nonce string) (*domain.ExternalIdentity, error) {
+	s.logger.Debug("Exchanging OIDC authorization code")
+	ctx, cancel := context.WithTimeout(oidc.ClientContext(ctx, s.client), time.Second*10)
+	defer cancel()
+
+	token, err := s.config.Exchange(ctx, code)
+	if err != nil {
+		return nil, &e.IdentityProviderError{
+			Kind:  domain.ErrUnauthorized,
+			Err:   "Code exchange error",
+			Cause: err,
+		}
+	}
+
+	raw, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, &e.IdentityProviderError{
+			Kind: domain.ErrUnauthorized,
+			Err:  "Identity provider returned no ID token",
+		}
+	}
+
+	idToken, err := s.verifier.Verify(ctx, raw)
+	if err != nil {
+		return nil, &e.IdentityProviderError{
+			Kind:  domain.ErrUnauthorized,
+			Err:   "ID token verification error",
+			Cause: err,
+		}
+	}
+
+	if idToken.Nonce != nonce {
+		return nil, &e.IdentityProviderError{
+			Kind: domain.ErrUnauthorized,
+			Err:  "ID token nonce mismatch",
+		}
+	}
+
+	var claims oidcClaims
+	err = idToken.Claims(&claims)
+	if err != nil {
+		return nil, &e.IdentityProviderError{
+			Kind:  domain.ErrUnauthorized,
+			Err:   "ID token claims error",
+			Cause: err,
+		}
+	}
+
+	return &domain.ExternalIdentity{
+		Provider:      s.name,
+		Subject:       idToken.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+	}, nil
+}
@@ -0,0 +1,158 @@
+package realization
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"merch/test/mocks"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// stubIdP - локальный провайдер OpenID Connect для тестов
+type stubIdP struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	claims jwt.MapClaims
+}
+
+func newStubIdP(t *testing.T) *stubIdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	idp := &stubIdP{key: key}
+	mux := http.NewServeMux()
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]any{
+			"issuer":                                idp.server.URL,
+			"authorization_endpoint":                idp.server.URL + "/authorize",
+			"token_endpoint":                        idp.server.URL + "/token",
+			"jwks_uri":                              idp.server.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]any{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"alg": "RS256",
+				"use": "sig",
+				"kid": "test",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("code") != "good-code" {
+			w.WriteHeader(http.StatusBadRequest)
+			writeJSON(w, map[string]string{"error": "invalid_grant"})
+			return
+		}
+
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, idp.claims)
+		token.Header["kid"] = "test"
+		idToken, err := token.SignedString(idp.key)
+		assert.NoError(t, err)
+
+		writeJSON(w, map[string]any{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     idToken,
+		})
+	})
+
+	return idp
+}
+
+func writeJSON(w http.ResponseWriter, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+func setupOIDC(t *testing.T) (*OIDC, *stubIdP) {
+	mockLogger := new(mocks.LoggerRepo)
+	mockLogger.On("Debug", mock.Anything).Maybe()
+
+	idp := newStubIdP(t)
+	idp.claims = jwt.MapClaims{
+		"iss":            idp.server.URL,
+		"aud":            "merch",
+		"sub":            "subject-1",
+		"email":          "user@example.com",
+		"email_verified": true,
+		"nonce":          "nonce",
+		"iat":            time.Now().Unix(),
+		"exp":            time.Now().Add(time.Hour).Unix(),
+	}
+
+	provider, err := NewOIDC("corp", idp.server.URL, "merch", "secret", "http://localhost/api/auth/sso/corp/callback", idp.server.Client(), mockLogger)
+	assert.NoError(t, err)
+
+	return provider, idp
+}
+
+func TestOIDC_AuthCodeURL(t *testing.T) {
+	provider, idp := setupOIDC(t)
+
+	authURL, err := url.Parse(provider.AuthCodeURL("state", "nonce"))
+	assert.NoError(t, err)
+	assert.Equal(t, idp.server.URL+"/authorize", authURL.Scheme+"://"+authURL.Host+authURL.Path)
+
+	query := authURL.Query()
+	assert.Equal(t, "code", query.Get("response_type"))
+	assert.Equal(t, "merch", query.Get("client_id"))
+	assert.Equal(t, "state", query.Get("state"))
+	assert.Equal(t, "nonce", query.Get("nonce"))
+	assert.Contains(t, query.Get("scope"), "openid")
+}
+
+func TestOIDC_Exchange(t *testing.T) {
+	provider, _ := setupOIDC(t)
+
+	identity, err := provider.Exchange(context.Background(), "good-code", "nonce")
+	assert.NoError(t, err)
+	assert.Equal(t, "corp", identity.Provider)
+	assert.Equal(t, "subject-1", identity.Subject)
+	assert.Equal(t, "user@example.com", identity.Email)
+	assert.True(t, identity.EmailVerified)
+}
+
+func TestOIDC_ExchangeErrors(t *testing.T) {
+	provider, idp := setupOIDC(t)
+
+	// Неверный код
+	_, err := provider.Exchange(context.Background(), "bad-code", "nonce")
+	assert.Error(t, err)
+
+	// Nonce не совпадает с сохраненным при начале входа
+	_, err = provider.Exchange(context.Background(), "good-code", "another")
+	assert.Error(t, err)
+
+	// Токен выпущен для другого клиента
+	idp.claims["aud"] = "another-client"
+	_, err = provider.Exchange(context.Background(), "good-code", "nonce")
+	assert.Error(t, err)
+
+	// Токен подписан чужим ключом
+	idp.claims["aud"] = "merch"
+	idp.key, _ = rsa.GenerateKey(rand.Reader, 2048)
+	_, err = provider.Exchange(context.Background(), "good-code", "nonce")
+	assert.Error(t, err)
+}
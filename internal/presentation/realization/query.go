@@ -0,0 +1,34 @@
+package realization
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// DEFAULT_QUERY_TIMEOUT - время выполнения одного запроса к базе данных по умолчанию
+const DEFAULT_QUERY_TIMEOUT = 3 * time.Second
+
+// queryContext ограничивает время выполнения запроса. Запрос также отменяется,
+// если отменен родительский контекст, например клиент закрыл соединение
+func queryContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, timeout)
+}
+
+// ReadRouter выбирает подключение для запроса только на чтение, например реплику базы данных
+type ReadRouter interface {
+	Reader(ctx context.Context) *sql.DB
+}
+
+// reader возвращает подключение для чтения от read или db, если read не задан
+func reader(ctx context.Context, db *sql.DB, read ReadRouter) *sql.DB {
+	if read == nil {
+		return db
+	}
+
+	return read.Reader(ctx)
+}
@@ -0,0 +1,94 @@
+package realization
+
+import (
+	"context"
+	"math"
+	"merch/internal/domain"
+	"sync"
+	"time"
+)
+
+// Как часто удалять из памяти полностью восполненные корзины
+const rateLimitCleanupInterval = time.Minute
+
+// bucket - корзина токенов для одного ключа
+type bucket struct {
+	tokens  float64   // Текущее количество токенов
+	updated time.Time // Время последнего пересчета
+	full    time.Time // Время, когда корзина полностью восполнится
+}
+
+// MemoryRateLimiter - хранилище корзин для ограничения частоты запросов в памяти процесса
+type MemoryRateLimiter struct {
+	mu          sync.Mutex
+	buckets     map[string]*bucket
+	now         func() time.Time
+	lastCleanup time.Time
+}
+
+// NewMemoryRateLimiter создает новый экземпляр MemoryRateLimiter
+func NewMemoryRateLimiter() *MemoryRateLimiter {
+	return &MemoryRateLimiter{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Allow списывает токен из корзины по ключу и сообщает, разрешен ли запрос
+func (s *MemoryRateLimiter) Allow(ctx context.Context, key string, limit domain.RateLimit) (*domain.RateLimitResult, error) {
+	if !limit.Enabled() {
+		return &domain.RateLimitResult{Allowed: true}, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.cleanup(now)
+
+	capacity := float64(limit.Burst)
+	rate := float64(limit.Requests) / limit.Period.Seconds() // Токенов в секунду
+
+	b, exists := s.buckets[key]
+	if !exists {
+		b = &bucket{tokens: capacity}
+		s.buckets[key] = b
+	} else {
+		b.tokens = math.Min(capacity, b.tokens+now.Sub(b.updated).Seconds()*rate)
+	}
+	b.updated = now
+
+	result := &domain.RateLimitResult{Limit: limit.Burst}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - b.tokens) / rate)
+	}
+
+	result.Remaining = int(b.tokens)
+	result.Reset = seconds((capacity - b.tokens) / rate)
+	b.full = now.Add(result.Reset)
+
+	return result, nil
+}
+
+// cleanup удаляет корзины, которые уже полностью восполнились - они не отличаются от новых
+func (s *MemoryRateLimiter) cleanup(now time.Time) {
+	if now.Sub(s.lastCleanup) < rateLimitCleanupInterval {
+		return
+	}
+
+	for key, b := range s.buckets {
+		if !now.Before(b.full) {
+			delete(s.buckets, key)
+		}
+	}
+
+	s.lastCleanup = now
+}
+
+func seconds(value float64) time.Duration {
+	return time.Duration(value * float64(time.Second))
+}
@@ -0,0 +1,67 @@
+package realization
+
+import (
+	"context"
+	"merch/internal/domain"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryRateLimiter_Allow(t *testing.T) {
+	now := time.Now()
+	limiter := NewMemoryRateLimiter()
+	limiter.now = func() time.Time { return now }
+	limit := domain.RateLimit{Requests: 1, Period: time.Second, Burst: 2}
+
+	result, err := limiter.Allow(context.Background(), "key", limit)
+	assert.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 2, result.Limit)
+	assert.Equal(t, 1, result.Remaining)
+
+	result, _ = limiter.Allow(context.Background(), "key", limit)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+	assert.Equal(t, 2*time.Second, result.Reset)
+
+	// Корзина пуста - запрос отклоняется
+	result, _ = limiter.Allow(context.Background(), "key", limit)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, time.Second, result.RetryAfter)
+
+	// Другой ключ учитывается отдельно
+	result, _ = limiter.Allow(context.Background(), "other", limit)
+	assert.True(t, result.Allowed)
+
+	// Через секунду восполняется один токен
+	now = now.Add(time.Second)
+	result, _ = limiter.Allow(context.Background(), "key", limit)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+}
+
+func TestMemoryRateLimiter_Cleanup(t *testing.T) {
+	now := time.Now()
+	limiter := NewMemoryRateLimiter()
+	limiter.now = func() time.Time { return now }
+	limit := domain.RateLimit{Requests: 10, Period: time.Second, Burst: 10}
+
+	_, _ = limiter.Allow(context.Background(), "key", limit)
+	assert.Len(t, limiter.buckets, 1)
+
+	now = now.Add(rateLimitCleanupInterval)
+	_, _ = limiter.Allow(context.Background(), "other", limit)
+	assert.Len(t, limiter.buckets, 1)
+	assert.Contains(t, limiter.buckets, "other")
+}
+
+func TestMemoryRateLimiter_Disabled(t *testing.T) {
+	limiter := NewMemoryRateLimiter()
+
+	result, err := limiter.Allow(context.Background(), "key", domain.RateLimit{})
+	assert.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Empty(t, limiter.buckets)
+}
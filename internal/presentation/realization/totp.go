@@ -0,0 +1,104 @@
+package realization
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"merch/internal/domain"
+	e "merch/internal/presentation/customError"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Параметры TOTP, совместимые с распространенными приложениями-аутентификаторами
+const (
+	totpDigits     = 6
+	totpPeriod     = 30 * time.Second
+	totpSecretSize = 20 // 160 бит, как рекомендует RFC 4226
+	totpSkew       = 1  // Сколько соседних шагов принимается из-за расхождения часов
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TOTP - генерация и проверка одноразовых паролей по RFC 6238
+type TOTP struct {
+	issuer string
+}
+
+// NewTOTP создает новый экземпляр TOTP с названием сервиса, которое увидит пользователь в приложении
+func NewTOTP(issuer string) *TOTP {
+	return &TOTP{
+		issuer: issuer,
+	}
+}
+
+// GenerateSecret создает новый случайный секрет в кодировке base32
+func (s *TOTP) GenerateSecret() (string, error) {
+	secret := make([]byte, totpSecretSize)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return "", &e.OTPError{
+			Kind:  domain.ErrInternal,
+			Err:   "Generating secret error",
+			Cause: err,
+		}
+	}
+
+	return totpEncoding.EncodeToString(secret), nil
+}
+
+// URI возвращает ссылку otpauth:// для подключения приложения-аутентификатора
+func (s *TOTP) URI(secret, account string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", s.issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(totpDigits))
+	query.Set("period", fmt.Sprint(int(totpPeriod.Seconds())))
+
+	label := url.PathEscape(s.issuer + ":" + account)
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
+
+// Verify проверяет код с учетом расхождения часов и возвращает номер шага, которому он соответствует
+func (s *TOTP) Verify(secret, code string, at time.Time) (int64, bool) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil || len(code) != totpDigits {
+		return 0, false
+	}
+
+	current := at.Unix() / int64(totpPeriod.Seconds())
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		expected := hotp(key, step)
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
+
+// hotp вычисляет одноразовый пароль по RFC 4226 для значения счетчика
+func hotp(key []byte, counter int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(counter))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < totpDigits; i++ {
+		mod *= 10
+	}
+
+	return fmt.Sprintf("%0*d", totpDigits, value%mod)
+}
@@ -0,0 +1,57 @@
+package realization
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Тестовый вектор из RFC 6238 для SHA1 (последние 6 цифр)
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestTOTP_VerifyRFCVectors(t *testing.T) {
+	totp := NewTOTP("Merch")
+
+	vectors := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+
+	for unix, code := range vectors {
+		step, ok := totp.Verify(rfcSecret, code, time.Unix(unix, 0))
+		assert.True(t, ok, "time %d", unix)
+		assert.Equal(t, unix/30, step)
+	}
+}
+
+func TestTOTP_VerifySkew(t *testing.T) {
+	totp := NewTOTP("Merch")
+	at := time.Unix(59, 0)
+
+	_, ok := totp.Verify(rfcSecret, "287082", at.Add(30*time.Second))
+	assert.True(t, ok)
+
+	_, ok = totp.Verify(rfcSecret, "287082", at.Add(90*time.Second))
+	assert.False(t, ok)
+
+	_, ok = totp.Verify(rfcSecret, "28708", at)
+	assert.False(t, ok)
+}
+
+func TestTOTP_GenerateSecretAndURI(t *testing.T) {
+	totp := NewTOTP("Merch")
+
+	secret, err := totp.GenerateSecret()
+	assert.NoError(t, err)
+	assert.Len(t, secret, 32)
+
+	uri := totp.URI(secret, "user@example.com")
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/Merch:user@example.com?"))
+	assert.Contains(t, uri, "secret="+secret)
+	assert.Contains(t, uri, "issuer=Merch")
+}
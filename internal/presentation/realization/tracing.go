@@ -0,0 +1,78 @@
+package realization
+
+import (
+	"context"
+	"fmt"
+	"merch/internal/domain"
+	e "merch/internal/presentation/customError"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// Tracing - провайдер трассировки OpenTelemetry
+type Tracing struct {
+	Provider trace.TracerProvider
+	shutdown func(ctx context.Context) error
+}
+
+// NewTracing создает провайдер трассировки с указанным экспортером.
+// Для domain.TRACING_OTLP пустой endpoint означает адрес из OTEL_EXPORTER_OTLP_ENDPOINT или http://localhost:4318
+func NewTracing(ctx context.Context, exporter, endpoint, service string) (*Tracing, error) {
+	var (
+		spanExporter sdktrace.SpanExporter
+		err          error
+	)
+
+	switch exporter {
+	case domain.TRACING_NONE, "":
+		return &Tracing{
+			Provider: noop.NewTracerProvider(),
+			shutdown: func(context.Context) error { return nil },
+		}, nil
+	case domain.TRACING_OTLP:
+		var options []otlptracehttp.Option
+		if endpoint != "" {
+			options = append(options, otlptracehttp.WithEndpointURL(endpoint))
+		}
+		spanExporter, err = otlptracehttp.New(ctx, options...)
+	case domain.TRACING_STDOUT:
+		spanExporter, err = stdouttrace.New()
+	default:
+		err = fmt.Errorf("unknown exporter %q", exporter)
+	}
+
+	if err != nil {
+		return nil, &e.TracingError{
+			Kind:  domain.ErrInternal,
+			Err:   "Creating trace exporter error",
+			Cause: err,
+		}
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(spanExporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", service))),
+	)
+
+	return &Tracing{
+		Provider: provider,
+		shutdown: provider.Shutdown,
+	}, nil
+}
+
+// Shutdown отправляет накопленные спаны и останавливает провайдер
+func (t *Tracing) Shutdown(ctx context.Context) error {
+	return t.shutdown(ctx)
+}
+
+// Propagator возвращает формат передачи контекста трассировки между сервисами (W3C Trace Context и Baggage)
+func Propagator() propagation.TextMapPropagator {
+	return propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+}
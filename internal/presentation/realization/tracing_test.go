@@ -0,0 +1,27 @@
+package realization
+
+import (
+	"context"
+	"merch/internal/domain"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestNewTracing(t *testing.T) {
+	tracing, err := NewTracing(context.Background(), domain.TRACING_NONE, "", "merch")
+	require.NoError(t, err)
+	assert.IsType(t, noop.TracerProvider{}, tracing.Provider)
+	assert.NoError(t, tracing.Shutdown(context.Background()))
+
+	tracing, err = NewTracing(context.Background(), domain.TRACING_OTLP, "http://localhost:4318", "merch")
+	require.NoError(t, err)
+	assert.IsType(t, &sdktrace.TracerProvider{}, tracing.Provider)
+	assert.NoError(t, tracing.Shutdown(context.Background()))
+
+	_, err = NewTracing(context.Background(), "jaeger", "", "merch")
+	assert.ErrorContains(t, err, `unknown exporter "jaeger"`)
+}
@@ -4,56 +4,65 @@ import (
 	"context"
 	"database/sql"
 	"errors"
-	"fmt"
 	"merch/internal/domain"
+	"merch/internal/interfaces"
 	e "merch/internal/presentation/customError"
-	"merch/internal/presentation/postgres"
-	"net/http"
 	"time"
 )
 
 // Transaction - структура для работы с транзакциями
-type Transaction struct{}
+type Transaction struct {
+    db      *sql.DB
+    read    ReadRouter
+    logger  interfaces.LoggerRepo
+    timeout time.Duration
+}
 
-// NewTransaction создает новый экземпляр Transaction
-func NewTransaction() *Transaction {
-    return &Transaction{}
+// NewTransaction создает новый экземпляр Transaction с подключением к базе данных, выбором подключения для чтения и временем выполнения одного запроса.
+// Если read равен nil, запросы на чтение тоже выполняются через db
+func NewTransaction(db *sql.DB, read ReadRouter, logger interfaces.LoggerRepo, timeout time.Duration) *Transaction {
+    return &Transaction{
+        db:      db,
+        read:    read,
+        logger:  logger,
+        timeout: timeout,
+    }
 }
 
 // Transfer выполняет перевод средств
-func (s *Transaction) Transfer(transaction domain.Transaction) error {
-    LoggerService.Debug("Transferring")
+func (s *Transaction) Transfer(ctx context.Context, transaction domain.Transaction) error {
+    s.logger.Debug("Transferring")
 
-    tx, err := postgres.DbService.Db.Begin()
+    tx, err := s.db.BeginTx(ctx, nil)
     if err != nil {
         return createTransactionError(err)
     }
 
     // Проверка наличия пользователя получателя
-    receiverExists, err := s.userExists(tx, transaction.ReceiverName)
+    receiverExists, err := s.userExists(ctx, tx, transaction.ReceiverName)
     if err != nil {
         return s.rollbackTransaction(tx, err)
     }
     if !receiverExists {
         err = tx.Rollback()
         if err != nil {
-            LoggerService.Error(fmt.Sprintf("Rollback error: %v", err))
+            s.logger.Error("Rollback error", domain.Field("error", err.Error()))
         }
         return &e.TransactionError{
-            Code: http.StatusBadRequest,
+            Kind: domain.ErrInvalidInput,
             Err:  "Receiver does not exist",
         }
     }
 
-    if err := s.updateUserCoins(tx, transaction.ReceiverName, transaction.Amount); err != nil {
+    if err := s.updateUserCoins(ctx, tx, transaction.ReceiverName, transaction.Amount); err != nil {
         return s.rollbackTransaction(tx, err)
     }
 
-    if err := s.updateUserCoins(tx, transaction.SenderName, -transaction.Amount); err != nil {
+    if err := s.updateUserCoins(ctx, tx, transaction.SenderName, -transaction.Amount); err != nil {
         return s.rollbackTransaction(tx, err)
     }
 
-    if err := s.insertTransaction(tx, transaction); err != nil {
+    if err := s.insertTransaction(ctx, tx, transaction); err != nil {
         return s.rollbackTransaction(tx, err)
     }
 
@@ -64,8 +73,8 @@ func (s *Transaction) Transfer(transaction domain.Transaction) error {
     return nil
 }
 
-func (s *Transaction) updateUserCoins(tx *sql.Tx, email string, amount int) error {
-    ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+func (s *Transaction) updateUserCoins(ctx context.Context, tx *sql.Tx, email string, amount int) error {
+    ctx, cancel := queryContext(ctx, s.timeout)
     defer cancel()
     _, err := tx.ExecContext(ctx, `UPDATE Users SET "coins" = "coins" + $1 WHERE "email" = $2`, amount, email)
     if err != nil {
@@ -74,8 +83,8 @@ func (s *Transaction) updateUserCoins(tx *sql.Tx, email string, amount int) erro
     return nil
 }
 
-func (s *Transaction) insertTransaction(tx *sql.Tx, transaction domain.Transaction) error {
-    ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+func (s *Transaction) insertTransaction(ctx context.Context, tx *sql.Tx, transaction domain.Transaction) error {
+    ctx, cancel := queryContext(ctx, s.timeout)
     defer cancel()
     _, err := tx.ExecContext(ctx, `INSERT INTO Transaction("sender_name", "receiver_name", "amount") VALUES ($1, $2, $3)`, transaction.SenderName, transaction.ReceiverName, transaction.Amount)
     if err != nil {
@@ -86,13 +95,13 @@ func (s *Transaction) insertTransaction(tx *sql.Tx, transaction domain.Transacti
 
 func (s *Transaction) rollbackTransaction(tx *sql.Tx, originalErr error) error {
     if rbErr := tx.Rollback(); rbErr != nil {
-        LoggerService.Error(fmt.Sprintf("Rollback error: %v", rbErr))
+        s.logger.Error("Rollback error", domain.Field("error", rbErr.Error()))
     }
     return originalErr
 }
 
-func (s *Transaction) userExists(tx *sql.Tx, email string) (bool, error) {
-    ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+func (s *Transaction) userExists(ctx context.Context, tx *sql.Tx, email string) (bool, error) {
+    ctx, cancel := queryContext(ctx, s.timeout)
     defer cancel()
     var exists bool
     err := tx.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM Users WHERE "email" = $1)`, email).Scan(&exists)
@@ -104,44 +113,48 @@ func (s *Transaction) userExists(tx *sql.Tx, email string) (bool, error) {
 
 func createTransactionError(err error) error {
     return &e.TransactionError{
-        Code: http.StatusInternalServerError,
-        Err:  fmt.Sprintf("Creating transaction error: %v", err),
+        Kind:  domain.ErrInternal,
+        Err:   "Creating transaction error",
+        Cause: err,
     }
 }
 
 func createDbQueryError(err error) error {
     return &e.DbQueryError{
-        Code: http.StatusInternalServerError,
-        Err:  fmt.Sprintf("Db query error: %v", err),
+        Kind:  domain.ErrInternal,
+        Err:   "Db query error",
+        Cause: err,
     }
 }
 
 func createCommitError(err error) error {
     return &e.TransactionError{
-        Code: http.StatusInternalServerError,
-        Err:  fmt.Sprintf("Commit error: %v", err),
+        Kind:  domain.ErrInternal,
+        Err:   "Commit error",
+        Cause: err,
     }
 }
 
 // GetTransaction получает транзакции для указанного пользователя по email
-func (s *Transaction) GetTransaction(user domain.UserEmail) (*[]domain.Transaction, error) {
-    LoggerService.Debug("Getting transaction")
-    ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+func (s *Transaction) GetTransaction(ctx context.Context, user domain.UserEmail) (*[]domain.Transaction, error) {
+    s.logger.Debug("Getting transaction")
+    ctx, cancel := queryContext(ctx, s.timeout)
     defer cancel()
-    rows, err := postgres.DbService.Db.QueryContext(ctx, `SELECT "id", "sender_name", "receiver_name", "amount" FROM Transaction WHERE "sender_name" = $1 OR "receiver_name" = $1`, user)
+    rows, err := reader(ctx, s.db, s.read).QueryContext(ctx, `SELECT "id", "sender_name", "receiver_name", "amount" FROM Transaction WHERE "sender_name" = $1 OR "receiver_name" = $1`, user)
     if err != nil {
         if errors.Is(err, sql.ErrNoRows) {
             return nil, nil
         }
         return nil, &e.DbQueryError{
-            Code: http.StatusInternalServerError,
-            Err:  fmt.Sprintf("Query db error: %v", err),
+            Kind:  domain.ErrInternal,
+            Err:   "Query db error",
+            Cause: err,
         }
     }
     defer func() {
         err := rows.Close()
         if err != nil {
-            LoggerService.Error(fmt.Sprintf("Error closing rows: %v", err))
+            s.logger.Error("Error closing rows", domain.Field("error", err.Error()))
         }
     }()
 
@@ -151,8 +164,9 @@ func (s *Transaction) GetTransaction(user domain.UserEmail) (*[]domain.Transacti
         err = rows.Scan(&transaction.Id, &transaction.SenderName, &transaction.ReceiverName, &transaction.Amount)
         if err != nil {
             return nil, &e.DbQueryError{
-                Code: http.StatusInternalServerError,
-                Err:  fmt.Sprintf("Scanning subject error: %v", err),
+                Kind:  domain.ErrInternal,
+                Err:   "Scanning subject error",
+                Cause: err,
             }
         }
         transactions = append(transactions, transaction)
@@ -0,0 +1,169 @@
+package realization
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"merch/internal/domain"
+	"merch/internal/interfaces"
+	"time"
+)
+
+// TwoFactor - структура для хранения настроек двухфакторной аутентификации
+type TwoFactor struct {
+	db      *sql.DB
+	logger  interfaces.LoggerRepo
+	timeout time.Duration
+}
+
+// NewTwoFactor создает новый экземпляр TwoFactor с подключением к базе данных и временем выполнения одного запроса
+func NewTwoFactor(db *sql.DB, logger interfaces.LoggerRepo, timeout time.Duration) *TwoFactor {
+	return &TwoFactor{
+		db:      db,
+		logger:  logger,
+		timeout: timeout,
+	}
+}
+
+// Get получает настройки двухфакторной аутентификации пользователя
+func (s *TwoFactor) Get(ctx context.Context, userId domain.UserId) (*domain.TwoFactor, error) {
+	s.logger.Debug("Getting two-factor settings")
+	twoFactor := domain.TwoFactor{UserId: userId}
+	ctx, cancel := queryContext(ctx, s.timeout)
+	defer cancel()
+	err := s.db.QueryRowContext(ctx, `SELECT "secret", "enabled", "last_step" FROM TwoFactor WHERE "user_id" = $1`, userId).Scan(&twoFactor.Secret, &twoFactor.Enabled, &twoFactor.LastStep)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, createDbQueryError(err)
+	}
+
+	return &twoFactor, nil
+}
+
+// SetSecret сохраняет новый неподтвержденный секрет
+func (s *TwoFactor) SetSecret(ctx context.Context, userId domain.UserId, secret string) error {
+	s.logger.Debug("Setting two-factor secret")
+	ctx, cancel := queryContext(ctx, s.timeout)
+	defer cancel()
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO TwoFactor ("user_id", "secret") VALUES ($1, $2)
+		ON CONFLICT ("user_id") DO UPDATE SET "secret" = $2, "enabled" = FALSE, "last_step" = 0`, userId, secret)
+
+	if err != nil {
+		return createDbQueryError(err)
+	}
+
+	return nil
+}
+
+// Enable подтверждает подключение и заменяет резервные коды
+func (s *TwoFactor) Enable(ctx context.Context, userId domain.UserId, recoveryHashes []string) error {
+	s.logger.Debug("Enabling two-factor authentication")
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return createTransactionError(err)
+	}
+
+	ctx, cancel := queryContext(ctx, s.timeout)
+	defer cancel()
+
+	_, err = tx.ExecContext(ctx, `UPDATE TwoFactor SET "enabled" = TRUE WHERE "user_id" = $1`, userId)
+	if err != nil {
+		return s.rollback(tx, createDbQueryError(err))
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM RecoveryCode WHERE "user_id" = $1`, userId)
+	if err != nil {
+		return s.rollback(tx, createDbQueryError(err))
+	}
+
+	for _, hash := range recoveryHashes {
+		_, err = tx.ExecContext(ctx, `INSERT INTO RecoveryCode ("user_id", "hash") VALUES ($1, $2)`, userId, hash)
+		if err != nil {
+			return s.rollback(tx, createDbQueryError(err))
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return createCommitError(err)
+	}
+
+	return nil
+}
+
+// Disable отключает двухфакторную аутентификацию и удаляет резервные коды
+func (s *TwoFactor) Disable(ctx context.Context, userId domain.UserId) error {
+	s.logger.Debug("Disabling two-factor authentication")
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return createTransactionError(err)
+	}
+
+	ctx, cancel := queryContext(ctx, s.timeout)
+	defer cancel()
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM RecoveryCode WHERE "user_id" = $1`, userId)
+	if err != nil {
+		return s.rollback(tx, createDbQueryError(err))
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM TwoFactor WHERE "user_id" = $1`, userId)
+	if err != nil {
+		return s.rollback(tx, createDbQueryError(err))
+	}
+
+	if err := tx.Commit(); err != nil {
+		return createCommitError(err)
+	}
+
+	return nil
+}
+
+// UseStep отмечает временной шаг использованным, если он новее последнего использованного
+func (s *TwoFactor) UseStep(ctx context.Context, userId domain.UserId, step int64) (bool, error) {
+	ctx, cancel := queryContext(ctx, s.timeout)
+	defer cancel()
+	result, err := s.db.ExecContext(ctx, `UPDATE TwoFactor SET "last_step" = $2 WHERE "user_id" = $1 AND "last_step" < $2`, userId, step)
+
+	if err != nil {
+		return false, createDbQueryError(err)
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return false, createDbQueryError(err)
+	}
+
+	return updated == 1, nil
+}
+
+// UseRecoveryCode погашает неиспользованный резервный код
+func (s *TwoFactor) UseRecoveryCode(ctx context.Context, userId domain.UserId, hash string) (bool, error) {
+	ctx, cancel := queryContext(ctx, s.timeout)
+	defer cancel()
+	result, err := s.db.ExecContext(ctx, `UPDATE RecoveryCode SET "used_at" = NOW() WHERE "user_id" = $1 AND "hash" = $2 AND "used_at" IS NULL`, userId, hash)
+
+	if err != nil {
+		return false, createDbQueryError(err)
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return false, createDbQueryError(err)
+	}
+
+	return updated > 0, nil
+}
+
+// rollback откатывает транзакцию и возвращает исходную ошибку
+func (s *TwoFactor) rollback(tx *sql.Tx, originalErr error) error {
+	if rbErr := tx.Rollback(); rbErr != nil {
+		s.logger.Error("Rollback error", domain.Field("error", rbErr.Error()))
+	}
+
+	return originalErr
+}
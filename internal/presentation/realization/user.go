@@ -4,34 +4,44 @@ import (
 	"context"
 	"database/sql"
 	"errors"
-	"fmt"
 	"merch/internal/domain"
+	"merch/internal/interfaces"
 	e "merch/internal/presentation/customError"
-	"merch/internal/presentation/postgres"
-	"net/http"
 	"time"
 )
 
 // User - структуру для работы с пользователями
-type User struct{}
+type User struct {
+	db      *sql.DB
+	read    ReadRouter
+	logger  interfaces.LoggerRepo
+	timeout time.Duration
+}
 
-// NewUser создает новый экземпляр User
-func NewUser() *User {
-	return &User{}
+// NewUser создает новый экземпляр User с подключением к базе данных, выбором подключения для чтения и временем выполнения одного запроса.
+// Если read равен nil, запросы на чтение тоже выполняются через db
+func NewUser(db *sql.DB, read ReadRouter, logger interfaces.LoggerRepo, timeout time.Duration) *User {
+	return &User{
+		db:      db,
+		read:    read,
+		logger:  logger,
+		timeout: timeout,
+	}
 }
 
 // Create создает нового пользователя
-func (s *User) Create(user domain.User) (*domain.UserId, error) {
-	LoggerService.Debug("Creating user")
+func (s *User) Create(ctx context.Context, user domain.User) (*domain.UserId, error) {
+	s.logger.Debug("Creating user")
 	var id uint64
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	ctx, cancel := queryContext(ctx, s.timeout)
 	defer cancel()
-	err := postgres.DbService.Db.QueryRowContext(ctx, `INSERT INTO Users ("email", "password", "coins") VALUES ($1, $2, $3) RETURNING "id"`, user.Email, user.Password, user.Coins).Scan(&id)
+	err := s.db.QueryRowContext(ctx, `INSERT INTO Users ("email", "password", "coins", "role") VALUES ($1, $2, $3, $4) RETURNING "id"`, user.Email, user.Password, user.Coins, user.Role).Scan(&id)
 
 	if err != nil {
 		return nil, &e.UserCreatingError{
-			Code: http.StatusInternalServerError,
-			Err:  fmt.Sprintf("User creating error: %v", err),
+			Kind:  domain.ErrInternal,
+			Err:   "User creating error",
+			Cause: err,
 		}
 	}
 
@@ -39,37 +49,54 @@ func (s *User) Create(user domain.User) (*domain.UserId, error) {
 }
 
 // GetById получает пользователя по его идентификатору
-func (s *User) GetById(id domain.UserId) (*domain.User, error) {
-	LoggerService.Debug("Getting user by id")
-	var user domain.User
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
-	defer cancel()
-	err := postgres.DbService.Db.QueryRowContext(ctx, `SELECT "id", "email", "password", "coins" FROM Users WHERE "id" = $1`, id).Scan(&user.Id, &user.Email, &user.Password, &user.Coins)
+func (s *User) GetById(ctx context.Context, id domain.UserId) (*domain.User, error) {
+	s.logger.Debug("Getting user by id")
+	db := reader(ctx, s.db, s.read)
+	user, err := s.getById(ctx, db, id)
+
+	// Только что созданного пользователя может еще не быть на реплике
+	if errors.Is(err, sql.ErrNoRows) && db != s.db {
+		user, err = s.getById(ctx, s.db, id)
+	}
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, &e.RowsNotFoundError{
-				Code: http.StatusBadRequest,
+				Kind: domain.ErrInvalidInput,
 				Err:  "User not exists",
 			}
 		}
 
 		return nil, &e.DbQueryError{
-			Code: http.StatusInternalServerError,
-			Err:  fmt.Sprintf("Query db error: %v", err),
+			Kind:  domain.ErrInternal,
+			Err:   "Query db error",
+			Cause: err,
 		}
 	}
 
+	return user, nil
+}
+
+// getById выполняет запрос пользователя по идентификатору через подключение db
+func (s *User) getById(ctx context.Context, db *sql.DB, id domain.UserId) (*domain.User, error) {
+	var user domain.User
+	ctx, cancel := queryContext(ctx, s.timeout)
+	defer cancel()
+	err := db.QueryRowContext(ctx, `SELECT "id", "email", "password", "coins", "role" FROM Users WHERE "id" = $1`, id).Scan(&user.Id, &user.Email, &user.Password, &user.Coins, &user.Role)
+	if err != nil {
+		return nil, err
+	}
+
 	return &user, nil
 }
 
 // GetByEmail получает пользователя по его email
-func (s *User) GetByEmail(email domain.UserEmail) (*domain.User, error) {
-	LoggerService.Info("Getting user by email")
+func (s *User) GetByEmail(ctx context.Context, email domain.UserEmail) (*domain.User, error) {
+	s.logger.Info("Getting user by email")
 	var user domain.User
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	ctx, cancel := queryContext(ctx, s.timeout)
 	defer cancel()
-	err := postgres.DbService.Db.QueryRowContext(ctx, `SELECT "id", "email", "password", "coins" FROM Users WHERE "email" = $1`, email).Scan(&user.Id, &user.Email, &user.Password, &user.Coins)
+	err := s.db.QueryRowContext(ctx, `SELECT "id", "email", "password", "coins", "role" FROM Users WHERE "email" = $1`, email).Scan(&user.Id, &user.Email, &user.Password, &user.Coins, &user.Role)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -77,10 +104,95 @@ func (s *User) GetByEmail(email domain.UserEmail) (*domain.User, error) {
 		}
 
 		return nil, &e.DbQueryError{
-			Code: http.StatusInternalServerError,
-			Err:  fmt.Sprintf("Query db error: %v", err),
+			Kind:  domain.ErrInternal,
+			Err:   "Query db error",
+			Cause: err,
 		}
 	}
 
 	return &user, nil
 }
+
+// SetRole изменяет роль пользователя с указанным email
+func (s *User) SetRole(ctx context.Context, email domain.UserEmail, role domain.Role) error {
+	s.logger.Debug("Setting user role")
+	ctx, cancel := queryContext(ctx, s.timeout)
+	defer cancel()
+	result, err := s.db.ExecContext(ctx, `UPDATE Users SET "role" = $1 WHERE "email" = $2`, role, email)
+
+	if err != nil {
+		return &e.DbQueryError{
+			Kind:  domain.ErrInternal,
+			Err:   "Query db error",
+			Cause: err,
+		}
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return &e.DbQueryError{
+			Kind:  domain.ErrInternal,
+			Err:   "Query db error",
+			Cause: err,
+		}
+	}
+
+	if updated == 0 {
+		return &e.RowsNotFoundError{
+			Kind: domain.ErrInvalidInput,
+			Err:  "User not exists",
+		}
+	}
+
+	return nil
+}
+
+// AddCoins начисляет монеты получателю и записывает начисление с API-ключом, которым оно выполнено, в одной транзакции
+func (s *User) AddCoins(ctx context.Context, grant domain.Grant) (err error) {
+	s.logger.Debug("Adding coins")
+	ctx, cancel := queryContext(ctx, s.timeout)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return createTransactionError(err)
+	}
+	defer func() {
+		if err != nil {
+			rbErr := tx.Rollback()
+			if rbErr != nil {
+				s.logger.Error("Rollback error", domain.Field("error", rbErr.Error()))
+			}
+		}
+	}()
+
+	result, err := tx.ExecContext(ctx, `UPDATE Users SET "coins" = "coins" + $1 WHERE "email" = $2`, grant.Amount, grant.ReceiverName)
+	if err != nil {
+		return createDbQueryError(err)
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return createDbQueryError(err)
+	}
+
+	if updated == 0 {
+		err = &e.RowsNotFoundError{
+			Kind: domain.ErrInvalidInput,
+			Err:  "User not exists",
+		}
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO CoinGrant ("api_key_id", "receiver_name", "amount") VALUES ($1, $2, $3)`, grant.ApiKeyId, grant.ReceiverName, grant.Amount)
+	if err != nil {
+		return createDbQueryError(err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return createCommitError(err)
+	}
+
+	return nil
+}
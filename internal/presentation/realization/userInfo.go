@@ -0,0 +1,102 @@
+package realization
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"merch/internal/domain"
+	"merch/internal/interfaces"
+	e "merch/internal/presentation/customError"
+	"time"
+)
+
+// USER_INFO_QUERY собирает баланс, инвентарь по видам предметов и историю переводов за один запрос.
+// Инвентарь и переводы возвращаются JSON массивами, пустая история - пустым массивом.
+// Начисления интеграций входят в полученные монеты после переводов, отправителем указывается название API-ключа
+const USER_INFO_QUERY = `SELECT u."coins",
+	COALESCE((SELECT json_agg(json_build_object('type', i."subject_name", 'quantity', i."quantity") ORDER BY i."subject_name")
+		FROM (SELECT "subject_name", COUNT(*) AS "quantity" FROM Inventory WHERE "user_id" = u."id" GROUP BY "subject_name") i), '[]'),
+	COALESCE((SELECT json_agg(json_build_object('user', r."user", 'amount', r."amount") ORDER BY r."grant", r."id")
+		FROM (SELECT t."sender_name" AS "user", t."amount", t."id", false AS "grant"
+				FROM Transaction t WHERE t."receiver_name" = u."email" AND t."sender_name" <> u."email"
+			UNION ALL
+			SELECT k."name", g."amount", g."id", true
+				FROM CoinGrant g JOIN ApiKey k ON k."id" = g."api_key_id" WHERE g."receiver_name" = u."email") r), '[]'),
+	COALESCE((SELECT json_agg(json_build_object('user', t."receiver_name", 'amount', t."amount") ORDER BY t."id")
+		FROM Transaction t WHERE t."sender_name" = u."email"), '[]')
+FROM Users u WHERE u."id" = $1`
+
+// UserInfo - структура для чтения сводной информации о пользователе
+type UserInfo struct {
+	db      *sql.DB
+	read    ReadRouter
+	logger  interfaces.LoggerRepo
+	timeout time.Duration
+}
+
+// NewUserInfo создает новый экземпляр UserInfo с подключением к базе данных, выбором подключения для чтения и временем выполнения одного запроса.
+// Если read равен nil, запросы выполняются через db
+func NewUserInfo(db *sql.DB, read ReadRouter, logger interfaces.LoggerRepo, timeout time.Duration) *UserInfo {
+	return &UserInfo{
+		db:      db,
+		read:    read,
+		logger:  logger,
+		timeout: timeout,
+	}
+}
+
+// GetInfo получает баланс, инвентарь и историю переводов пользователя по его идентификатору
+func (s *UserInfo) GetInfo(ctx context.Context, userId domain.UserId) (*domain.UserInfo, error) {
+	s.logger.Debug("Getting user info")
+	db := reader(ctx, s.db, s.read)
+	info, err := s.getInfo(ctx, db, userId)
+
+	// Только что созданного пользователя может еще не быть на реплике
+	if errors.Is(err, sql.ErrNoRows) && db != s.db {
+		info, err = s.getInfo(ctx, s.db, userId)
+	}
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &e.RowsNotFoundError{
+				Kind: domain.ErrInvalidInput,
+				Err:  "User not exists",
+			}
+		}
+
+		return nil, &e.DbQueryError{
+			Kind:  domain.ErrInternal,
+			Err:   "Query db error",
+			Cause: err,
+		}
+	}
+
+	return info, nil
+}
+
+// getInfo выполняет USER_INFO_QUERY через подключение db и разбирает JSON массивы
+func (s *UserInfo) getInfo(ctx context.Context, db *sql.DB, userId domain.UserId) (*domain.UserInfo, error) {
+	var (
+		info                      domain.UserInfo
+		inventory, received, sent []byte
+	)
+
+	ctx, cancel := queryContext(ctx, s.timeout)
+	defer cancel()
+	err := db.QueryRowContext(ctx, USER_INFO_QUERY, userId).Scan(&info.Coins, &inventory, &received, &sent)
+	if err != nil {
+		return nil, err
+	}
+
+	err = errors.Join(
+		json.Unmarshal(inventory, &info.Inventory),
+		json.Unmarshal(received, &info.Received),
+		json.Unmarshal(sent, &info.Sent),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &info, nil
+}
@@ -0,0 +1,90 @@
+package realization
+
+import (
+	"context"
+	"database/sql"
+	"merch/internal/domain"
+	"merch/test/mocks"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// replicaRouter всегда выбирает реплику
+type replicaRouter struct {
+	replica *sql.DB
+}
+
+func (r replicaRouter) Reader(ctx context.Context) *sql.DB {
+	return r.replica
+}
+
+func newSqlMock(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
+	db, sqlMock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	return db, sqlMock
+}
+
+func userInfoRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"coins", "inventory", "received", "sent"})
+}
+
+func TestUserInfo_GetInfo(t *testing.T) {
+	mockLogger := new(mocks.LoggerRepo)
+	mockLogger.On("Debug", mock.Anything).Maybe()
+	db, sqlMock := newSqlMock(t)
+	repo := NewUserInfo(db, nil, mockLogger, 50*time.Millisecond)
+
+	sqlMock.ExpectQuery(`SELECT u."coins"`).WithArgs(uint64(1)).WillReturnRows(userInfoRows().AddRow(
+		940,
+		[]byte(`[{"type": "cup", "quantity": 2}, {"type": "pen", "quantity": 1}]`),
+		[]byte(`[{"user": "sender@example.com", "amount": 30}]`),
+		[]byte(`[]`),
+	))
+
+	info, err := repo.GetInfo(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, &domain.UserInfo{
+		Coins:     940,
+		Inventory: []domain.ItemQuantity{{Type: "cup", Quantity: 2}, {Type: "pen", Quantity: 1}},
+		Received:  []domain.CoinTransfer{{User: "sender@example.com", Amount: 30}},
+		Sent:      []domain.CoinTransfer{},
+	}, info)
+
+	sqlMock.ExpectQuery(`SELECT u."coins"`).WithArgs(uint64(2)).WillReturnRows(userInfoRows())
+	_, err = repo.GetInfo(context.Background(), 2)
+	assert.ErrorIs(t, err, domain.ErrInvalidInput)
+
+	sqlMock.ExpectQuery(`SELECT u."coins"`).WithArgs(uint64(3)).WillReturnRows(userInfoRows().AddRow(0, []byte(`{`), []byte(`[]`), []byte(`[]`)))
+	_, err = repo.GetInfo(context.Background(), 3)
+	assert.ErrorIs(t, err, domain.ErrInternal)
+
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestUserInfo_GetInfoFallsBackToPrimary(t *testing.T) {
+	mockLogger := new(mocks.LoggerRepo)
+	mockLogger.On("Debug", mock.Anything).Maybe()
+	primary, primaryMock := newSqlMock(t)
+	replica, replicaMock := newSqlMock(t)
+	repo := NewUserInfo(primary, replicaRouter{replica: replica}, mockLogger, 50*time.Millisecond)
+
+	// Пользователь только что создан и еще не дошел до реплики
+	replicaMock.ExpectQuery(`SELECT u."coins"`).WithArgs(uint64(1)).WillReturnRows(userInfoRows())
+	primaryMock.ExpectQuery(`SELECT u."coins"`).WithArgs(uint64(1)).WillReturnRows(userInfoRows().AddRow(1000, []byte(`[]`), []byte(`[]`), []byte(`[]`)))
+
+	info, err := repo.GetInfo(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, 1000, info.Coins)
+
+	assert.NoError(t, replicaMock.ExpectationsWereMet())
+	assert.NoError(t, primaryMock.ExpectationsWereMet())
+}
@@ -0,0 +1,36 @@
+package realization
+
+import (
+	"context"
+	"merch/internal/domain"
+	"merch/test/mocks"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestUser_AddCoins(t *testing.T) {
+	mockLogger := new(mocks.LoggerRepo)
+	mockLogger.On("Debug", mock.Anything).Maybe()
+	db, sqlMock := newSqlMock(t)
+	repo := NewUser(db, nil, mockLogger, 50*time.Millisecond)
+	grant := domain.Grant{ApiKeyId: 3, ReceiverName: "user@example.com", Amount: 100}
+
+	// Начисление и запись о нем сохраняются в одной транзакции
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectExec(`UPDATE Users SET "coins"`).WithArgs(100, "user@example.com").WillReturnResult(sqlmock.NewResult(0, 1))
+	sqlMock.ExpectExec(`INSERT INTO CoinGrant`).WithArgs(uint64(3), "user@example.com", 100).WillReturnResult(sqlmock.NewResult(1, 1))
+	sqlMock.ExpectCommit()
+	assert.NoError(t, repo.AddCoins(context.Background(), grant))
+
+	// Без получателя начисление не записывается
+	sqlMock.ExpectBegin()
+	sqlMock.ExpectExec(`UPDATE Users SET "coins"`).WithArgs(100, "user@example.com").WillReturnResult(sqlmock.NewResult(0, 0))
+	sqlMock.ExpectRollback()
+	assert.ErrorIs(t, repo.AddCoins(context.Background(), grant), domain.ErrInvalidInput)
+
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
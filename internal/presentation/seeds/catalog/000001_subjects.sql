@@ -0,0 +1,14 @@
+-- Каталог товаров магазина, нужен в каждом окружении. Первоначальный каталог создает миграция 000001,
+-- цена из файла считается актуальной и обновляется при каждом применении
+INSERT INTO Subject (name, cost) VALUES
+    ('t-shirt', 80),
+    ('cup', 20),
+    ('book', 50),
+    ('pen', 10),
+    ('powerbank', 200),
+    ('hoody', 300),
+    ('umbrella', 200),
+    ('socks', 10),
+    ('wallet', 50),
+    ('pink-hoody', 500)
+ON CONFLICT (name) DO UPDATE SET cost = EXCLUDED.cost;
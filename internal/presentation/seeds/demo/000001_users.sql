@@ -0,0 +1,9 @@
+-- Демонстрационные пользователи для локальной разработки и стендов, пароль у всех - Password123.
+-- Существующие пользователи не изменяются, чтобы повторное применение не сбрасывало их баланс
+INSERT INTO Users (email, password, coins, role) VALUES
+    ('demo@example.com', '008c70392e3abfbd0fa47bbc2ed96aa99bd49e159727fcba0f2e6abeb3a9d601', 1000, 'user'),
+    ('rich@example.com', '008c70392e3abfbd0fa47bbc2ed96aa99bd49e159727fcba0f2e6abeb3a9d601', 100000, 'user'),
+    ('broke@example.com', '008c70392e3abfbd0fa47bbc2ed96aa99bd49e159727fcba0f2e6abeb3a9d601', 0, 'user'),
+    ('admin@example.com', '008c70392e3abfbd0fa47bbc2ed96aa99bd49e159727fcba0f2e6abeb3a9d601', 1000, 'admin'),
+    ('auditor@example.com', '008c70392e3abfbd0fa47bbc2ed96aa99bd49e159727fcba0f2e6abeb3a9d601', 1000, 'auditor')
+ON CONFLICT (email) DO NOTHING;
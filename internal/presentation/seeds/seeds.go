@@ -0,0 +1,47 @@
+package seeds
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// FS - файлы начальных данных, встроенные в бинарный файл. Каждый набор - директория с файлами
+// {номер}_{название}.sql, которые применяются по порядку номеров. Запросы в файлах должны быть идемпотентными
+// (INSERT ... ON CONFLICT), чтобы набор можно было применять повторно
+//
+//go:embed */*.sql
+var FS embed.FS
+
+// Sets возвращает имена всех наборов, встроенных в бинарный файл. Должны совпадать с domain.SEED_SETS
+func Sets() []string {
+	entries, err := fs.ReadDir(FS, ".")
+	if err != nil {
+		return nil
+	}
+
+	sets := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			sets = append(sets, entry.Name())
+		}
+	}
+
+	return sets
+}
+
+// Files возвращает пути файлов набора в порядке применения
+func Files(set string) ([]string, error) {
+	entries, err := fs.ReadDir(FS, set)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			files = append(files, set+"/"+entry.Name())
+		}
+	}
+
+	return files, nil
+}
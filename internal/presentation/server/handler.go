@@ -1,12 +1,20 @@
 package server
 
 import (
+	"context"
+	"crypto/subtle"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"merch/internal/domain"
+	"merch/internal/interfaces"
 	e "merch/internal/presentation/customError"
+	"merch/internal/presentation/postgres"
 	"merch/internal/presentation/realization"
+	"merch/internal/services"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -31,85 +39,95 @@ type coinHistory struct {
 	Sent     []SenderTransaction   `json:"sent"`
 }
 
+type roleForm struct {
+	Role string `json:"role"`
+}
+
+type apiKeyForm struct {
+	Name      string   `json:"name"`
+	Scopes    []string `json:"scopes"`
+	ExpiresIn int      `json:"expiresIn"` // Срок действия в секундах, 0 - бессрочный
+}
+
+type issuedKeyForm struct {
+	Key    string         `json:"key"`
+	ApiKey *domain.ApiKey `json:"apiKey"`
+}
+
+type secondFactorForm struct {
+	Challenge string `json:"challenge"`
+	Code      string `json:"code"`
+}
+
 type userForm struct {
 	Coins       int             `json:"coins"`
 	Inventory   []inventoryForm `json:"inventory"`
 	CoinHistory coinHistory     `json:"coinHistory"`
 }
 
-// Handlers определяет хендлеры для обработки HTTP-запросов
-type Handlers struct{}
+type errorForm struct {
+	Code      string         `json:"code"`                // Стабильный код категории ошибки
+	Message   string         `json:"message"`             // Описание ошибки для человека
+	Details   map[string]any `json:"details,omitempty"`   // Дополнительные сведения, например поле с ошибкой
+	RequestId string         `json:"requestId,omitempty"` // Совпадает с заголовком X-Request-ID и полем requestId в логах
+}
+
+// Handlers определяет хендлеры для обработки HTTP-запросов и сервисы, которые они используют
+type Handlers struct {
+	money     *services.MoneyService
+	user      *services.UserService
+	guard     *services.LoginGuardService
+	keys      *services.ApiKeyService
+	twoFactor *services.TwoFactorService
+	identity  *services.IdentityService
+	health    *services.HealthService
+	db        *postgres.DB
+	logger    interfaces.LoggerRepo
+}
 
 // NewHandlers создает новый экземпляр Handlers
-func NewHandlers() *Handlers {
-	return &Handlers{}
+func NewHandlers(deps Deps) *Handlers {
+	return &Handlers{
+		money:     deps.Money,
+		user:      deps.User,
+		guard:     deps.Guard,
+		keys:      deps.Keys,
+		twoFactor: deps.TwoFactor,
+		identity:  deps.Identity,
+		health:    deps.Health,
+		db:        deps.Db,
+		logger:    deps.Logger,
+	}
 }
 
 // GetInfo возвращает информацию о пользователе
-func (*Handlers) GetInfo(ctx *gin.Context) {
-	token := getJWT(ctx)
+func (h *Handlers) GetInfo(ctx *gin.Context) {
+	token := h.getJWT(ctx)
 
 	if token == nil {
-		answerError(ctx, &e.NeedAuthorization{
-			Code: http.StatusUnauthorized,
+		h.answerError(ctx, &e.NeedAuthorization{
+			Kind: domain.ErrUnauthorized,
 			Err:  "No cookie",
 		})
 		return
 	}
 
-	info, err := UserService.GetInfo(token.Id)
+	info, err := h.user.GetInfo(h.readContext(ctx, token.Id), token.Id)
 	if err != nil {
-		answerError(ctx, err)
+		h.answerError(ctx, err)
 		return
 	}
 
-	inventory := make(map[string]int)
-
-	for _, subject := range info.Inventory {
-		quantity, exists := inventory[subject.Subject]
-
-		if !exists {
-			quantity = 0
-		}
-
-		inventory[subject.Subject] = quantity + 1
-	}
-
-	var userInfo userForm
-	userInfo.Coins = info.Coins
-	for name, quantity := range inventory {
-		userInfo.Inventory = append(userInfo.Inventory, inventoryForm{
-			Type:     name,
-			Quantity: quantity,
-		})
-	}
-
-	var coinTransactions coinHistory
-	for _, transaction := range info.Transactions {
-		if transaction.SenderName == token.Email {
-			coinTransactions.Sent = append(coinTransactions.Sent, SenderTransaction{
-				ToUser: transaction.ReceiverName,
-				Amount: transaction.Amount,
-			})
-		} else {
-			coinTransactions.Received = append(coinTransactions.Received, recieverTransaction{
-				FromUser: transaction.SenderName,
-				Amount:   transaction.Amount,
-			})
-		}
-	}
-
-	userInfo.CoinHistory = coinTransactions
-	ctx.JSON(http.StatusOK, userInfo)
+	ctx.JSON(http.StatusOK, newUserForm(info))
 }
 
 // SendCoin обрабатывает запрос на отправку монет
-func (*Handlers) SendCoin(ctx *gin.Context) {
-	token := getJWT(ctx)
+func (h *Handlers) SendCoin(ctx *gin.Context) {
+	token := h.getJWT(ctx)
 
 	if token == nil {
-		answerError(ctx, &e.NeedAuthorization{
-			Code: http.StatusUnauthorized,
+		h.answerError(ctx, &e.NeedAuthorization{
+			Kind: domain.ErrUnauthorized,
 			Err:  "No cookie",
 		})
 		return
@@ -120,42 +138,48 @@ func (*Handlers) SendCoin(ctx *gin.Context) {
 	defer func() {
 		err := ctx.Request.Body.Close()
 		if err != nil {
-			realization.LoggerService.Error(fmt.Sprintf("Error closing body: %v", err))
+			h.log(ctx).Error("Error closing body", domain.Field("error", err.Error()))
 		}
 	}()
 
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, map[string]string{"errors": STATUS_BAD_REQUEST})
-		ctx.Abort()
+		h.answerError(ctx, &e.InvalidRequest{
+			Kind:  domain.ErrInvalidInput,
+			Err:   STATUS_BAD_REQUEST,
+			Cause: err,
+		})
 		return
 	}
 
 	if data.ToUser == token.Email {
-		answerError(ctx, &e.TransactionError{
-			Code: http.StatusBadRequest,
+		h.answerError(ctx, &e.TransactionError{
+			Kind: domain.ErrInvalidInput,
 			Err:  "Translation for yourself",
 		})
+		return
 	}
 
 	transaction := domain.CreateTransaction(token.Email, data.ToUser, data.Amount)
-	err = BuyService.MoneyTransfer(*transaction)
+	err = h.money.MoneyTransfer(postgres.WithPrimary(ctx.Request.Context()), *transaction)
 
 	if err != nil {
-		answerError(ctx, err)
+		h.answerError(ctx, err)
 		return
 	}
 
+	h.markWrite(token.Id)
+
 	ctx.Status(http.StatusOK)
 }
 
 // BuyMerch обрабатывает запрос на покупку товара
-func (*Handlers) BuyMerch(ctx *gin.Context) {
+func (h *Handlers) BuyMerch(ctx *gin.Context) {
 	item := ctx.Param("item")
-	token := getJWT(ctx)
+	token := h.getJWT(ctx)
 
 	if token == nil {
-		answerError(ctx, &e.NeedAuthorization{
-			Code: http.StatusUnauthorized,
+		h.answerError(ctx, &e.NeedAuthorization{
+			Kind: domain.ErrUnauthorized,
 			Err:  "No cookie",
 		})
 		return
@@ -166,85 +190,605 @@ func (*Handlers) BuyMerch(ctx *gin.Context) {
 		UserId:  token.Id,
 	}
 
-	err := BuyService.BuyMerch(inventory)
+	err := h.money.BuyMerch(postgres.WithPrimary(ctx.Request.Context()), inventory)
 	if err != nil {
-		answerError(ctx, err)
+		h.answerError(ctx, err)
 		return
 	}
 
+	h.markWrite(token.Id)
+
 	ctx.Status(http.StatusOK)
 }
 
 // Auth обрабатывает запрос на авторизацию пользователя
-func (*Handlers) Auth(ctx *gin.Context) {
+func (h *Handlers) Auth(ctx *gin.Context) {
 	var data domain.AuthorizationData
 	err := json.NewDecoder(ctx.Request.Body).Decode(&data)
 	defer func() {
 		err := ctx.Request.Body.Close()
 		if err != nil {
-			realization.LoggerService.Error(fmt.Sprintf("Error closing body: %v", err))
+			h.log(ctx).Error("Error closing body", domain.Field("error", err.Error()))
 		}
 	}()
 
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, map[string]string{"errors5": fmt.Sprintf("%s: %s", STATUS_BAD_REQUEST, "check input data")})
+		h.answerError(ctx, &e.InvalidRequest{
+			Kind:  domain.ErrInvalidInput,
+			Err:   STATUS_BAD_REQUEST,
+			Cause: err,
+		})
 		return
 	}
 
 	if !IsValidEmail(data.Username) {
-		ctx.JSON(http.StatusBadRequest, map[string]string{"errors6": fmt.Sprintf("%s: %s invalid email", STATUS_BAD_REQUEST, data.Username)})
+		h.answerError(ctx, &e.InvalidRequest{
+			Kind:    domain.ErrInvalidInput,
+			Err:     "Invalid email",
+			Details: map[string]any{"field": "username"},
+		})
 		return
 	}
 
 	pass, err := ValidPass(data.Password)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, map[string]string{"errors": fmt.Sprintf("%s: %v", STATUS_BAD_REQUEST, err)})
+		h.answerError(ctx, err)
 		return
 	}
 
 	data.Password = pass
-	token, err := UserService.Login(data)
+
+	ip := ctx.ClientIP()
+	if h.loginLocked(ctx, data.Username, ip) {
+		return
+	}
+
+	result, err := h.user.Login(ctx.Request.Context(), data)
+	if err != nil {
+		h.loginFailed(ctx, data.Username, ip, err)
+		return
+	}
+
+	h.loginSucceeded(ctx, data.Username)
+	ctx.JSON(http.StatusOK, result)
+}
+
+// AuthSecondFactor завершает вход с двухфакторной аутентификацией: обменивает токен подтверждения и код на токен доступа
+func (h *Handlers) AuthSecondFactor(ctx *gin.Context) {
+	var data secondFactorForm
+	err := json.NewDecoder(ctx.Request.Body).Decode(&data)
+	defer func() {
+		err := ctx.Request.Body.Close()
+		if err != nil {
+			h.log(ctx).Error("Error closing body", domain.Field("error", err.Error()))
+		}
+	}()
+
+	if err != nil {
+		h.answerError(ctx, &e.InvalidRequest{
+			Kind:  domain.ErrInvalidInput,
+			Err:   STATUS_BAD_REQUEST,
+			Cause: err,
+		})
+		return
+	}
+
+	claims, err := h.twoFactor.Challenge(data.Challenge)
+	if err != nil {
+		h.answerError(ctx, err)
+		return
+	}
+
+	ip := ctx.ClientIP()
+	if h.loginLocked(ctx, claims.Email, ip) {
+		return
+	}
+
+	token, err := h.twoFactor.Complete(ctx.Request.Context(), claims, data.Code)
+	if err != nil {
+		h.loginFailed(ctx, claims.Email, ip, err)
+		return
+	}
+
+	h.loginSucceeded(ctx, claims.Email)
+	ctx.JSON(http.StatusOK, domain.LoginResult{Token: *token})
+}
+
+// SSOLogin перенаправляет пользователя на страницу входа внешнего провайдера.
+// state и nonce сохраняются в cookie и проверяются при возврате пользователя
+func (h *Handlers) SSOLogin(ctx *gin.Context) {
+	login, err := h.identity.Begin(ctx.Param("provider"))
+	if err != nil {
+		h.answerError(ctx, err)
+		return
+	}
+
+	ctx.SetSameSite(http.SameSiteLaxMode)
+	ctx.SetCookie(SSO_STATE_COOKIE, login.State+"."+login.Nonce, int(SSO_STATE_TTL.Seconds()), "/api/auth/sso", "", ctx.Request.TLS != nil, true)
+	ctx.Redirect(http.StatusFound, login.URL)
+}
+
+// SSOCallback принимает код авторизации от внешнего провайдера и выдает токен доступа
+func (h *Handlers) SSOCallback(ctx *gin.Context) {
+	cookie, err := ctx.Cookie(SSO_STATE_COOKIE)
+	ctx.SetCookie(SSO_STATE_COOKIE, "", -1, "/api/auth/sso", "", ctx.Request.TLS != nil, true)
+	if err != nil || ctx.Query("error") != "" {
+		h.answerError(ctx, &e.NeedAuthorization{
+			Kind: domain.ErrUnauthorized,
+			Err:  STATUS_UNAUTHORIZED,
+		})
+		return
+	}
+
+	state, nonce, found := strings.Cut(cookie, ".")
+	if !found || subtle.ConstantTimeCompare([]byte(state), []byte(ctx.Query("state"))) != 1 {
+		h.answerError(ctx, &e.NeedAuthorization{
+			Kind: domain.ErrUnauthorized,
+			Err:  STATUS_UNAUTHORIZED,
+		})
+		return
+	}
+
+	result, err := h.identity.Complete(ctx.Request.Context(), ctx.Param("provider"), ctx.Query("code"), nonce)
+	if err != nil {
+		h.answerError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// TwoFactorEnroll создает секрет TOTP для подключения приложения-аутентификатора
+func (h *Handlers) TwoFactorEnroll(ctx *gin.Context) {
+	token := h.getJWT(ctx)
+	if token == nil {
+		return
+	}
+
+	enrollment, err := h.twoFactor.Enroll(ctx.Request.Context(), token.Id, token.Email)
+	if err != nil {
+		h.answerError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, enrollment)
+}
+
+// TwoFactorConfirm включает двухфакторную аутентификацию и возвращает резервные коды
+func (h *Handlers) TwoFactorConfirm(ctx *gin.Context) {
+	token := h.getJWT(ctx)
+	if token == nil {
+		return
+	}
+
+	var data secondFactorForm
+	err := json.NewDecoder(ctx.Request.Body).Decode(&data)
+	defer func() {
+		err := ctx.Request.Body.Close()
+		if err != nil {
+			h.log(ctx).Error("Error closing body", domain.Field("error", err.Error()))
+		}
+	}()
+
+	if err != nil {
+		h.answerError(ctx, &e.InvalidRequest{
+			Kind:  domain.ErrInvalidInput,
+			Err:   STATUS_BAD_REQUEST,
+			Cause: err,
+		})
+		return
+	}
+
+	codes, err := h.twoFactor.Confirm(ctx.Request.Context(), token.Id, data.Code)
+	if err != nil {
+		h.answerError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, map[string][]string{"recoveryCodes": codes})
+}
+
+// TwoFactorDisable отключает двухфакторную аутентификацию
+func (h *Handlers) TwoFactorDisable(ctx *gin.Context) {
+	token := h.getJWT(ctx)
+	if token == nil {
+		return
+	}
+
+	var data secondFactorForm
+	err := json.NewDecoder(ctx.Request.Body).Decode(&data)
+	defer func() {
+		err := ctx.Request.Body.Close()
+		if err != nil {
+			h.log(ctx).Error("Error closing body", domain.Field("error", err.Error()))
+		}
+	}()
+
+	if err != nil {
+		h.answerError(ctx, &e.InvalidRequest{
+			Kind:  domain.ErrInvalidInput,
+			Err:   STATUS_BAD_REQUEST,
+			Cause: err,
+		})
+		return
+	}
+
+	err = h.twoFactor.Disable(ctx.Request.Context(), token.Id, data.Code)
+	if err != nil {
+		h.answerError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+// loginLocked проверяет блокировку входа для аккаунта и IP-адреса. Возвращает true, если ответ уже отправлен
+func (h *Handlers) loginLocked(ctx *gin.Context, email, ip string) bool {
+	retryAfter, err := h.guard.Check(ctx.Request.Context(), email, ip)
+	if err != nil {
+		h.answerError(ctx, err)
+		return true
+	}
+
+	if retryAfter > 0 {
+		h.tooManyAttempts(ctx, retryAfter)
+		return true
+	}
+
+	return false
+}
+
+// loginFailed отвечает ошибкой входа. Неверный пароль или код учитывается в счетчиках неудачных попыток
+func (h *Handlers) loginFailed(ctx *gin.Context, email, ip string, err error) {
+	if errors.Is(err, domain.ErrUnauthorized) {
+		h.log(ctx).Warn("Failed login attempt", domain.Field("email", email), domain.Field("ip", ip))
+
+		retryAfter, guardErr := h.guard.Fail(ctx.Request.Context(), email, ip)
+		if guardErr != nil {
+			h.answerError(ctx, guardErr)
+			return
+		}
+
+		if retryAfter > 0 {
+			h.tooManyAttempts(ctx, retryAfter)
+			return
+		}
+	}
+
+	h.answerError(ctx, err)
+}
+
+// loginSucceeded сбрасывает счетчик неудачных попыток аккаунта
+func (h *Handlers) loginSucceeded(ctx *gin.Context, email string) {
+	err := h.guard.Success(ctx.Request.Context(), email)
+	if err != nil {
+		h.log(ctx).Error("Resetting login attempts error", domain.Field("error", err.Error()))
+	}
+}
+
+// tooManyAttempts отвечает 429 с заголовком Retry-After в секундах
+func (h *Handlers) tooManyAttempts(ctx *gin.Context, retryAfter time.Duration) {
+	ctx.Header("Retry-After", strconv.Itoa(ceilSeconds(retryAfter)))
+	h.answerError(ctx, &e.TooManyAttempts{
+		Kind:    domain.ErrTooManyRequests,
+		Err:     "Too many failed login attempts",
+		Details: map[string]any{"retryAfter": ceilSeconds(retryAfter)},
+	})
+}
+
+// GetUserInfo возвращает информацию о любом пользователе по email для администраторов и аудиторов
+func (h *Handlers) GetUserInfo(ctx *gin.Context) {
+	email := ctx.Param("email")
+
+	info, err := h.user.GetInfoByEmail(ctx.Request.Context(), email)
+	if err != nil {
+		h.answerError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newUserForm(info))
+}
+
+// SetRole изменяет роль пользователя
+func (h *Handlers) SetRole(ctx *gin.Context) {
+	var data roleForm
+	err := json.NewDecoder(ctx.Request.Body).Decode(&data)
+	defer func() {
+		err := ctx.Request.Body.Close()
+		if err != nil {
+			h.log(ctx).Error("Error closing body", domain.Field("error", err.Error()))
+		}
+	}()
+
+	if err != nil {
+		h.answerError(ctx, &e.InvalidRequest{
+			Kind:  domain.ErrInvalidInput,
+			Err:   STATUS_BAD_REQUEST,
+			Cause: err,
+		})
+		return
+	}
+
+	err = h.user.SetRole(ctx.Request.Context(), ctx.Param("email"), data.Role)
+	if err != nil {
+		h.answerError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+// ListKeys возвращает все выданные API-ключи
+func (h *Handlers) ListKeys(ctx *gin.Context) {
+	keys, err := h.keys.List(ctx.Request.Context())
+	if err != nil {
+		h.answerError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, keys)
+}
+
+// IssueKey выпускает новый API-ключ. Ключ возвращается только в этом ответе
+func (h *Handlers) IssueKey(ctx *gin.Context) {
+	token := h.getJWT(ctx)
+	if token == nil {
+		return
+	}
+
+	var data apiKeyForm
+	err := json.NewDecoder(ctx.Request.Body).Decode(&data)
+	defer func() {
+		err := ctx.Request.Body.Close()
+		if err != nil {
+			h.log(ctx).Error("Error closing body", domain.Field("error", err.Error()))
+		}
+	}()
+
+	if err != nil || data.ExpiresIn < 0 {
+		h.answerError(ctx, &e.InvalidRequest{
+			Kind:  domain.ErrInvalidInput,
+			Err:   STATUS_BAD_REQUEST,
+			Cause: err,
+		})
+		return
+	}
+
+	raw, key, err := h.keys.Issue(ctx.Request.Context(), data.Name, data.Scopes, time.Duration(data.ExpiresIn)*time.Second, token.Id)
 	if err != nil {
-		answerError(ctx, err)
+		h.answerError(ctx, err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, map[string]string{"token": *token})
+	h.log(ctx).Info("API key issued", domain.Field("issuedKey", key.Prefix))
+	ctx.JSON(http.StatusCreated, issuedKeyForm{
+		Key:    raw,
+		ApiKey: key,
+	})
 }
 
-// answerError обрабатывает ошибки и возвращает соответствующий HTTP-статус
-func answerError(ctx *gin.Context, err error) {
-	baseErr := err.(*domain.BaseError)
+// RevokeKey отзывает API-ключ
+func (h *Handlers) RevokeKey(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		h.answerError(ctx, &e.InvalidRequest{
+			Kind:  domain.ErrInvalidInput,
+			Err:   STATUS_BAD_REQUEST,
+			Cause: err,
+		})
+		return
+	}
 
-	switch baseErr.GetCode() {
-	case http.StatusUnauthorized:
-		ctx.JSON(http.StatusUnauthorized, map[string]string{"errors": STATUS_UNAUTHORIZED})
-		ctx.Abort()
-	case http.StatusInternalServerError:
-		realization.LoggerService.Error(baseErr.Error())
-		ctx.JSON(http.StatusInternalServerError, map[string]string{"errors": STATUS_INTERNAL_SERVER})
-		ctx.Abort()
-	case http.StatusBadRequest:
-		ctx.JSON(http.StatusBadRequest, map[string]string{"errors": fmt.Sprintf("%s: %s", STATUS_BAD_REQUEST, baseErr.Error())})
-		ctx.Abort()
+	err = h.keys.Revoke(ctx.Request.Context(), id)
+	if err != nil {
+		h.answerError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+// ServiceBalance возвращает баланс пользователя для интеграции с правом balance:read
+func (h *Handlers) ServiceBalance(ctx *gin.Context) {
+	coins, err := h.user.GetBalance(ctx.Request.Context(), ctx.Param("email"))
+	if err != nil {
+		h.answerError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, map[string]int{"coins": coins})
+}
+
+// ServiceGrant начисляет монеты пользователю для интеграции с правом coins:grant
+func (h *Handlers) ServiceGrant(ctx *gin.Context) {
+	var data SenderTransaction
+	err := json.NewDecoder(ctx.Request.Body).Decode(&data)
+	defer func() {
+		err := ctx.Request.Body.Close()
+		if err != nil {
+			h.log(ctx).Error("Error closing body", domain.Field("error", err.Error()))
+		}
+	}()
+
+	if err != nil {
+		h.answerError(ctx, &e.InvalidRequest{
+			Kind:  domain.ErrInvalidInput,
+			Err:   STATUS_BAD_REQUEST,
+			Cause: err,
+		})
+		return
+	}
+
+	// RequireScope уже проверил, что запрос авторизован API-ключом
+	key := ctx.MustGet("apiKey").(*domain.ApiKey)
+	err = h.money.Grant(ctx.Request.Context(), domain.Grant{ApiKeyId: key.Id, ReceiverName: data.ToUser, Amount: data.Amount})
+	if err != nil {
+		h.answerError(ctx, err)
+		return
+	}
+
+	h.log(ctx).Info("Coins granted", domain.Field("amount", data.Amount), domain.Field("toUser", data.ToUser))
+	ctx.Status(http.StatusOK)
+}
+
+// newUserForm переводит информацию о пользователе в ответ API, пустые списки выводятся как []
+func newUserForm(info *domain.UserInfo) userForm {
+	userInfo := userForm{
+		Coins:     info.Coins,
+		Inventory: make([]inventoryForm, 0, len(info.Inventory)),
+		CoinHistory: coinHistory{
+			Received: make([]recieverTransaction, 0, len(info.Received)),
+			Sent:     make([]SenderTransaction, 0, len(info.Sent)),
+		},
+	}
+
+	for _, item := range info.Inventory {
+		userInfo.Inventory = append(userInfo.Inventory, inventoryForm{
+			Type:     item.Type,
+			Quantity: item.Quantity,
+		})
+	}
+
+	for _, transfer := range info.Received {
+		userInfo.CoinHistory.Received = append(userInfo.CoinHistory.Received, recieverTransaction{
+			FromUser: transfer.User,
+			Amount:   transfer.Amount,
+		})
+	}
+
+	for _, transfer := range info.Sent {
+		userInfo.CoinHistory.Sent = append(userInfo.CoinHistory.Sent, SenderTransaction{
+			ToUser: transfer.User,
+			Amount: transfer.Amount,
+		})
+	}
+
+	return userInfo
+}
+
+// NotFound отвечает на запрос к неизвестному адресу
+func (h *Handlers) NotFound(ctx *gin.Context) {
+	h.answerError(ctx, &e.InvalidRequest{
+		Kind: domain.ErrNotFound,
+		Err:  STATUS_NOT_FOUND,
+	})
+}
+
+// MethodNotAllowed отвечает на запрос с методом, который адрес не поддерживает
+func (h *Handlers) MethodNotAllowed(ctx *gin.Context) {
+	h.answerError(ctx, &e.InvalidRequest{
+		Kind: errMethodNotAllowed,
+		Err:  STATUS_NOT_ALLOWED,
+	})
+}
+
+// errMethodNotAllowed - категория запросов с методом, который адрес не поддерживает.
+// Относится только к HTTP, поэтому объявлена здесь, а не в domain
+var errMethodNotAllowed = errors.New("method_not_allowed")
+
+// errorStatuses сопоставляет категории ошибок с HTTP-статусами и сообщениями по умолчанию.
+// Ошибки других категорий и ошибки без категории считаются внутренними
+var errorStatuses = []struct {
+	kind    error
+	status  int
+	message string
+}{
+	{domain.ErrInvalidInput, http.StatusBadRequest, STATUS_BAD_REQUEST},
+	{domain.ErrInsufficientFunds, http.StatusBadRequest, STATUS_BAD_REQUEST},
+	{domain.ErrUnauthorized, http.StatusUnauthorized, STATUS_UNAUTHORIZED},
+	{domain.ErrForbidden, http.StatusForbidden, STATUS_FORBIDDEN},
+	{domain.ErrNotFound, http.StatusNotFound, STATUS_NOT_FOUND},
+	{domain.ErrTooManyRequests, http.StatusTooManyRequests, STATUS_TOO_MANY_REQUESTS},
+	{errMethodNotAllowed, http.StatusMethodNotAllowed, STATUS_NOT_ALLOWED},
+}
+
+// answerError отвечает на любую ошибку телом ErrorResponse и прерывает обработку запроса.
+// Внутренние ошибки пишутся в лог, а клиент получает только общее сообщение и идентификатор запроса
+func (h *Handlers) answerError(ctx *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	body := errorForm{
+		Code:      domain.ErrInternal.Error(),
+		Message:   STATUS_INTERNAL_SERVER,
+		RequestId: ctx.GetString(REQUEST_ID_KEY),
+	}
+
+	for _, mapping := range errorStatuses {
+		if errors.Is(err, mapping.kind) {
+			status = mapping.status
+			body.Code = mapping.kind.Error()
+			body.Message = mapping.message
+			break
+		}
+	}
+
+	var baseErr *domain.BaseError
+	if status == http.StatusInternalServerError {
+		h.log(ctx).Error("Request failed", domain.Field("error", err.Error()))
+	} else if errors.As(err, &baseErr) {
+		if baseErr.Err != "" {
+			body.Message = baseErr.Err
+		}
+		body.Details = baseErr.Details
+	}
+
+	ctx.AbortWithStatusJSON(status, body)
+}
+
+// readContext возвращает контекст запроса на чтение. Пользователь, который только что перевел монеты
+// или купил товар, читает с основного сервера, чтобы увидеть изменения, еще не дошедшие до реплик
+func (h *Handlers) readContext(ctx *gin.Context, userId domain.UserId) context.Context {
+	if h.db == nil {
+		return ctx.Request.Context()
+	}
+
+	return h.db.ReadContext(ctx.Request.Context(), userId)
+}
+
+// markWrite запоминает, что пользователь изменил данные
+func (h *Handlers) markWrite(userId domain.UserId) {
+	if h.db != nil {
+		h.db.MarkWrite(userId)
 	}
 }
 
 // getJWT извлекает JWT токен из контекста
-func getJWT(ctx *gin.Context) *realization.Token {
+func (h *Handlers) getJWT(ctx *gin.Context) *realization.Token {
 	tokenAny, exists := ctx.Get("token")
 
 	if !exists {
-		ctx.JSON(http.StatusUnauthorized, map[string]string{"errors": STATUS_UNAUTHORIZED})
-		ctx.Abort()
+		h.answerError(ctx, &e.NeedAuthorization{
+			Kind: domain.ErrUnauthorized,
+			Err:  STATUS_UNAUTHORIZED,
+		})
 		return nil
 	}
 
 	tokenStr := tokenAny.(string)
-	token, _ := UserService.Token(tokenStr)
+	token, _ := h.user.Token(tokenStr)
 
 	return &realization.Token{
 		Id:    token.Id,
 		Email: token.Email,
+		Role:  token.Role,
 	}
 }
+
+// Healthz сообщает, что процесс жив
+func (h *Handlers) Healthz(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, h.health.Live())
+}
+
+// Readyz сообщает, готов ли сервис принимать запросы, с результатом каждой проверки
+func (h *Handlers) Readyz(ctx *gin.Context) {
+	checkCtx, cancel := context.WithTimeout(ctx.Request.Context(), READY_TIMEOUT)
+	defer cancel()
+
+	report := h.health.Ready(checkCtx)
+	if !report.Healthy() {
+		h.log(ctx).Warn("Readiness check failed", domain.Field("checks", report.Checks))
+		ctx.JSON(http.StatusServiceUnavailable, report)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"encoding/json"
+	"merch/internal/domain"
+	"merch/internal/services"
+	"merch/test/mocks"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestSSOLoginAndCallback(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockProvider := new(mocks.MockIdentityProvider)
+	mockIdentityRepo := new(mocks.MockExternalIdentityRepo)
+	mockUserRepo := new(mocks.MockUserRepo)
+	mockAuthRepo := new(mocks.MockAuthRepo)
+	mockTwoFactorRepo := new(mocks.MockTwoFactorRepo)
+
+	var userId domain.UserId = 1
+	var token domain.Token = "token"
+	mockProvider.On("Name").Return("corp")
+	identityService := services.NewIdentityService(mockIdentityRepo, mockUserRepo, mockAuthRepo, mockTwoFactorRepo, mockProvider)
+	mockProvider.On("AuthCodeURL", mock.Anything, mock.Anything).Return("https://idp/authorize")
+	mockIdentityRepo.On("GetUserId", mock.Anything, "corp", "sub").Return(&userId, nil)
+	mockUserRepo.On("GetById", mock.Anything, userId).Return(&domain.User{Id: 1, Email: "user@example.com", Role: domain.ROLE_USER}, nil)
+	mockTwoFactorRepo.On("Get", mock.Anything, userId).Return((*domain.TwoFactor)(nil), nil)
+	mockAuthRepo.On("CreateToken", mock.Anything, mock.Anything).Return(&token, nil)
+
+	h := newTestHandlers(Deps{Identity: identityService})
+	router := gin.New()
+	router.GET("/api/auth/sso/:provider", h.SSOLogin)
+	router.GET("/api/auth/sso/:provider/callback", h.SSOCallback)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/auth/sso/corp", nil))
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "https://idp/authorize", w.Header().Get("Location"))
+
+	cookies := w.Result().Cookies()
+	assert.Len(t, cookies, 1)
+	assert.Equal(t, SSO_STATE_COOKIE, cookies[0].Name)
+	assert.True(t, cookies[0].HttpOnly)
+	state, nonce, _ := strings.Cut(cookies[0].Value, ".")
+	mockProvider.On("Exchange", mock.Anything, "code", nonce).Return(&domain.ExternalIdentity{Provider: "corp", Subject: "sub"}, nil)
+
+	callback := func(query string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/auth/sso/corp/callback?"+query, nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Без cookie и с чужим state вход отклоняется
+	assert.Equal(t, http.StatusUnauthorized, callback("code=code&state="+state, nil).Code)
+	assert.Equal(t, http.StatusUnauthorized, callback("code=code&state=forged", cookies[0]).Code)
+	assert.Equal(t, http.StatusUnauthorized, callback("error=access_denied&state="+state, cookies[0]).Code)
+	mockProvider.AssertNotCalled(t, "Exchange", mock.Anything, mock.Anything, mock.Anything)
+
+	w = callback("code=code&state="+state, cookies[0])
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var result domain.LoginResult
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, token, result.Token)
+}
+
+func TestSendCoin_ToYourself(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockAuthRepo := new(mocks.MockAuthRepo)
+	mockUserRepo := new(mocks.MockUserRepo)
+	mockTransactionRepo := new(mocks.MockTransactionRepo)
+	mockAuthRepo.On("DecodeToken", "token").Return(&domain.AuthorizationToken{Id: 1, Email: "user@example.com", Role: domain.ROLE_USER}, nil)
+
+	h := newTestHandlers(Deps{
+		User:  services.NewUserService(mockUserRepo, mockAuthRepo, new(mocks.MockUserInfoRepo), new(mocks.MockTwoFactorRepo), new(mocks.MockMetricsRepo), noop.NewTracerProvider()),
+		Money: services.NewMoneyService(mockUserRepo, mockTransactionRepo, new(mocks.MockInventoryRepo), new(mocks.MockMetricsRepo), noop.NewTracerProvider()),
+	})
+	router := gin.New()
+	router.POST("/api/sendCoin", func(ctx *gin.Context) { ctx.Set("token", "token") }, h.SendCoin)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/sendCoin", strings.NewReader(`{"toUser": "user@example.com", "amount": 10}`))
+	router.ServeHTTP(w, req)
+
+	// Перевод самому себе отклоняется одним ответом, без обращения к сервису переводов
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var body errorForm
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "Translation for yourself", body.Message)
+	mockUserRepo.AssertNotCalled(t, "GetByEmail", mock.Anything, mock.Anything)
+	mockTransactionRepo.AssertNotCalled(t, "Transfer", mock.Anything, mock.Anything)
+}
+
+func TestNewUserForm_EmptyHistory(t *testing.T) {
+	// Пользователь без предметов и переводов получает пустые массивы, а не null
+	body, err := json.Marshal(newUserForm(&domain.UserInfo{Coins: 1000}))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"coins":1000,"inventory":[],"coinHistory":{"received":[],"sent":[]}}`, string(body))
+}
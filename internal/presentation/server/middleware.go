@@ -1,69 +1,356 @@
 package server
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
-	"merch/internal/presentation/realization"
+	"math"
+	"merch/internal/domain"
+	"merch/internal/interfaces"
+	e "merch/internal/presentation/customError"
 	"net/http"
+	"runtime/debug"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-// LoggerMiddleware возвращает middleware, который логирует информацию о запросах
-func LoggerMiddleware() gin.HandlerFunc {
+// Ключи значений запроса в gin.Context
+const (
+	REQUEST_ID_KEY = "requestId" // Идентификатор запроса
+	LOGGER_KEY     = "logger"    // Логгер с полями запроса
+)
+
+// REQUEST_ID_HEADER - заголовок, в котором идентификатор запроса принимается от клиента или прокси и возвращается в ответе
+const REQUEST_ID_HEADER = "X-Request-ID"
+
+// MAX_REQUEST_ID_LENGTH - максимальная длина принимаемого идентификатора запроса
+const MAX_REQUEST_ID_LENGTH = 64
+
+// LoggerMiddleware возвращает middleware, который создает логгер запроса с его идентификатором
+// и после обработки записывает итог запроса. Идентификатор берется из заголовка X-Request-ID
+// или создается, если заголовка нет, и возвращается клиенту. Пользователя к логгеру добавляет AuthMiddleware
+func (h *Handlers) LoggerMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
 
+		requestId := c.GetHeader(REQUEST_ID_HEADER)
+		if !validRequestId(requestId) {
+			requestId = newRequestId()
+		}
+
+		c.Set(REQUEST_ID_KEY, requestId)
+		c.Header(REQUEST_ID_HEADER, requestId)
+		c.Set(LOGGER_KEY, h.logger.With(
+			domain.Field("requestId", requestId),
+			domain.Field("method", c.Request.Method),
+			domain.Field("path", c.Request.URL.Path),
+		))
+
 		c.Next()
 
-		realization.LoggerService.Info(fmt.Sprintf("Completed %s %s with %d in %v",
-			c.Request.Method,
-			c.Request.URL.Path,
-			c.Writer.Status(),
-			time.Since(start)))
+		h.log(c).Info("Request completed",
+			domain.Field("status", c.Writer.Status()),
+			domain.Field("latency", time.Since(start)),
+			domain.Field("ip", c.ClientIP()),
+		)
+	}
+}
+
+// log возвращает логгер текущего запроса, а если его нет - общий логгер
+func (h *Handlers) log(ctx *gin.Context) interfaces.LoggerRepo {
+	if logger, ok := ctx.Get(LOGGER_KEY); ok {
+		return logger.(interfaces.LoggerRepo)
+	}
+
+	return h.logger
+}
+
+// withLogFields добавляет поля к логгеру текущего запроса
+func (h *Handlers) withLogFields(ctx *gin.Context, fields ...domain.LogField) {
+	ctx.Set(LOGGER_KEY, h.log(ctx).With(fields...))
+}
+
+// newRequestId создает случайный идентификатор запроса
+func newRequestId() string {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
 	}
+
+	return hex.EncodeToString(b)
 }
 
-// AuthMiddleware проверяет JWT токен в заголовке авторизации
-func AuthMiddleware() gin.HandlerFunc {
+// validRequestId проверяет, что идентификатор от клиента не пустой, не слишком длинный
+// и состоит только из букв, цифр и символов "-", "_", ".", чтобы его нельзя было использовать для подделки логов
+func validRequestId(id string) bool {
+	if id == "" || len(id) > MAX_REQUEST_ID_LENGTH {
+		return false
+	}
+
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// MetricsMiddleware возвращает middleware, который учитывает количество и длительность запросов по маршрутам
+func (h *Handlers) MetricsMiddleware(metrics interfaces.MetricsRepo) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		// Шаблон маршрута вместо пути, чтобы параметры не раздували число меток
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		metrics.ObserveRequest(c.Request.Method, route, c.Writer.Status(), time.Since(start))
+	}
+}
+
+// RecoveryMiddleware возвращает middleware, который перехватывает панику в обработчике, пишет ее в лог
+// со стеком и полями запроса и отвечает стандартной ошибкой 500 без подробностей
+func (h *Handlers) RecoveryMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			r := recover()
+			if r == nil {
+				return
+			}
+
+			// Обрыв соединения, о котором сообщает сам net/http, перехватывать не нужно
+			if r == http.ErrAbortHandler {
+				panic(r)
+			}
+
+			h.log(c).Error("Panic recovered",
+				domain.Field("panic", fmt.Sprint(r)),
+				domain.Field("stack", string(debug.Stack())),
+			)
+
+			// Если ответ уже начат, другой статус отправить нельзя
+			if c.Writer.Written() {
+				c.Abort()
+				return
+			}
+
+			h.answerError(c, &e.PanicError{
+				Kind:  domain.ErrInternal,
+				Err:   "Panic in handler",
+				Cause: fmt.Errorf("%v", r),
+			})
+		}()
+
+		c.Next()
+	}
+}
+
+// AuthMiddleware проверяет JWT токен или API-ключ в заголовке авторизации
+func (h *Handlers) AuthMiddleware() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		// Извлекаем токен из заголовка авторизации
 		tokenStr := ctx.Request.Header.Get("Authorization")
 
 		parts := strings.Split(tokenStr, " ")
 		if len(parts) != 2 {
-			ctx.JSON(http.StatusUnauthorized, map[string]string{"errors": STATUS_UNAUTHORIZED})
-			ctx.Abort()
+			h.answerError(ctx, &e.NeedAuthorization{
+				Kind: domain.ErrUnauthorized,
+				Err:  STATUS_UNAUTHORIZED,
+			})
+			return
+		}
+
+		if parts[0] == API_KEY_SCHEME {
+			key, err := h.keys.Authenticate(ctx.Request.Context(), parts[1])
+			if err != nil {
+				h.answerError(ctx, err)
+				return
+			}
+
+			ctx.Set("apiKey", key)
+			h.withLogFields(ctx, domain.Field("apiKey", key.Prefix))
+			ctx.Next()
 			return
 		}
 
-		token, err := UserService.Token(parts[1])
+		token, err := h.user.Token(parts[1])
 
 		if err != nil {
-			answerError(ctx, err)
+			h.answerError(ctx, err)
 			return
 		}
 
 		if time.Now().Before(token.Expires) {
-			ctx.JSON(http.StatusUnauthorized, map[string]string{"errors": fmt.Sprintf("%s - %s", STATUS_UNAUTHORIZED, "token expired")})
-			ctx.Abort()
+			h.answerError(ctx, &e.NeedAuthorization{
+				Kind: domain.ErrUnauthorized,
+				Err:  "Token expired",
+			})
 			return
 		}
 
-		exists, err := UserService.Access(parts[1], token.Id)
+		exists, err := h.user.Access(ctx.Request.Context(), parts[1], token.Id)
 		if err != nil {
-			answerError(ctx, err)
+			h.answerError(ctx, err)
 			return
 		}
 
 		if !*exists {
-			ctx.JSON(http.StatusUnauthorized, map[string]string{"errors": fmt.Sprintf("%s - %s", STATUS_UNAUTHORIZED, "you are trying to log into someone else's account or the token for this account has been revoked")})
-			ctx.Abort()
+			h.answerError(ctx, &e.NeedAuthorization{
+				Kind: domain.ErrUnauthorized,
+				Err:  "You are trying to log into someone else's account or the token for this account has been revoked",
+			})
 			return
 		}
 
 		ctx.Set("token", parts[1])
+		h.withLogFields(ctx, domain.Field("userId", token.Id))
 		ctx.Next()
 	}
 }
+
+// RequireRole пропускает запрос, только если роль пользователя из JWT входит в список разрешенных.
+// Должен подключаться после AuthMiddleware
+func (h *Handlers) RequireRole(roles ...domain.Role) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		token := h.getJWT(ctx)
+		if token == nil {
+			return
+		}
+
+		for _, role := range roles {
+			if token.Role == role {
+				ctx.Next()
+				return
+			}
+		}
+
+		h.answerError(ctx, &e.AccessDenied{
+			Kind: domain.ErrForbidden,
+			Err:  fmt.Sprintf("Role %s has no access", token.Role),
+		})
+	}
+}
+
+// RequireScope пропускает запрос, только если он авторизован API-ключом с указанным правом доступа.
+// Должен подключаться после AuthMiddleware
+func (h *Handlers) RequireScope(scope string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		keyAny, exists := ctx.Get("apiKey")
+		if !exists {
+			h.answerError(ctx, &e.AccessDenied{
+				Kind: domain.ErrForbidden,
+				Err:  "API key required",
+			})
+			return
+		}
+
+		key := keyAny.(*domain.ApiKey)
+		if !key.HasScope(scope) {
+			h.answerError(ctx, &e.AccessDenied{
+				Kind: domain.ErrForbidden,
+				Err:  fmt.Sprintf("API key %s has no scope %s", key.Prefix, scope),
+			})
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// RateLimitConfig задает политики ограничения частоты запросов
+type RateLimitConfig struct {
+	Default domain.RateLimit            // Политика для маршрутов без отдельной настройки
+	Routes  map[string]domain.RateLimit // Политики по шаблонам маршрутов gin, например /api/buy/:item
+}
+
+// DefaultRateLimitConfig возвращает политики ограничения частоты запросов по умолчанию
+func DefaultRateLimitConfig() RateLimitConfig {
+	return RateLimitConfig{
+		Default: domain.RateLimit{Requests: 300, Period: time.Minute, Burst: 100},
+		Routes: map[string]domain.RateLimit{
+			"/api/auth":                        {Requests: 10, Period: time.Minute, Burst: 10},
+			"/api/auth/2fa":                    {Requests: 10, Period: time.Minute, Burst: 10},
+			"/api/auth/sso/:provider/callback": {Requests: 10, Period: time.Minute, Burst: 10},
+			"/api/sendCoin":                    {Requests: 60, Period: time.Minute, Burst: 20},
+			"/api/buy/:item":                   {Requests: 30, Period: time.Minute, Burst: 10},
+		},
+	}
+}
+
+// policy возвращает политику для маршрута
+func (c RateLimitConfig) policy(route string) domain.RateLimit {
+	if limit, exists := c.Routes[route]; exists {
+		return limit
+	}
+
+	return c.Default
+}
+
+// RateLimitMiddleware ограничивает частоту запросов по политикам маршрутов.
+// Запросы учитываются по идентификатору пользователя из JWT, а без токена - по IP-адресу клиента
+func (h *Handlers) RateLimitMiddleware(store interfaces.RateLimitRepo, config RateLimitConfig) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		route := ctx.FullPath()
+		limit := config.policy(route)
+
+		if !limit.Enabled() {
+			ctx.Next()
+			return
+		}
+
+		result, err := store.Allow(ctx.Request.Context(), route+"|"+h.rateLimitKey(ctx), limit)
+		if err != nil {
+			// Недоступность хранилища не должна останавливать сервис
+			h.log(ctx).Error("Rate limit store error", domain.Field("error", err.Error()))
+			ctx.Next()
+			return
+		}
+
+		ctx.Header("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+		ctx.Header("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		ctx.Header("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+
+		if !result.Allowed {
+			ctx.Header("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			h.answerError(ctx, &e.RateLimitExceeded{
+				Kind:    domain.ErrTooManyRequests,
+				Err:     "Rate limit exceeded",
+				Details: map[string]any{"retryAfter": ceilSeconds(result.RetryAfter)},
+			})
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// rateLimitKey возвращает ключ клиента: идентификатор пользователя из JWT или IP-адрес.
+// Запросы с API-ключом учитываются по IP-адресу: ключ проверяется в базе данных только в AuthMiddleware,
+// после лимита, а собственный лимит для каждого предъявленного ключа позволил бы обходить лимиты входа
+func (h *Handlers) rateLimitKey(ctx *gin.Context) string {
+	parts := strings.Split(ctx.Request.Header.Get("Authorization"), " ")
+	if len(parts) == 2 && parts[0] != API_KEY_SCHEME {
+		token, err := h.user.Token(parts[1])
+		if err == nil {
+			return fmt.Sprintf("user:%d", token.Id)
+		}
+	}
+
+	return "ip:" + ctx.ClientIP()
+}
+
+// ceilSeconds округляет длительность вверх до целых секунд
+func ceilSeconds(duration time.Duration) int {
+	return int(math.Ceil(duration.Seconds()))
+}
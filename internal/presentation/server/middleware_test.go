@@ -0,0 +1,382 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"merch/internal/domain"
+	e "merch/internal/presentation/customError"
+	"merch/internal/presentation/realization"
+	"merch/internal/services"
+	"merch/test/mocks"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace/noop"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// newTestHandlers создает хендлеры с указанными сервисами и логгером, принимающим любые сообщения
+func newTestHandlers(deps Deps) *Handlers {
+	logger := new(mocks.LoggerRepo)
+	logger.On("Debug", mock.Anything).Maybe()
+	logger.On("Debug", mock.Anything, mock.Anything).Maybe()
+	logger.On("Info", mock.Anything).Maybe()
+	logger.On("Info", mock.Anything, mock.Anything).Maybe()
+	logger.On("Warn", mock.Anything).Maybe()
+	logger.On("Warn", mock.Anything, mock.Anything).Maybe()
+	logger.On("Error", mock.Anything).Maybe()
+	logger.On("Error", mock.Anything, mock.Anything).Maybe()
+	deps.Logger = logger
+
+	return NewHandlers(deps)
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	config := RateLimitConfig{
+		Default: domain.RateLimit{Requests: 100, Period: time.Minute, Burst: 100},
+		Routes: map[string]domain.RateLimit{
+			"/api/buy/:item": {Requests: 1, Period: time.Minute, Burst: 2},
+		},
+	}
+
+	h := newTestHandlers(Deps{})
+	router := gin.New()
+	router.Use(h.RateLimitMiddleware(realization.NewMemoryRateLimiter(), config))
+	router.GET("/api/buy/:item", func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+	router.GET("/api/info", func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+
+	request := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Лимит считается по шаблону маршрута, а не по конкретному товару
+	w := request("/api/buy/cup")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "1", w.Header().Get("X-RateLimit-Remaining"))
+
+	w = request("/api/buy/pen")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "0", w.Header().Get("X-RateLimit-Remaining"))
+
+	w = request("/api/buy/cup")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+	assert.Equal(t, "120", w.Header().Get("X-RateLimit-Reset"))
+
+	// Для остальных маршрутов действует политика по умолчанию
+	w = request("/api/info")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "100", w.Header().Get("X-RateLimit-Limit"))
+}
+
+func TestRateLimitMiddleware_ApiKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	config := RateLimitConfig{Default: domain.RateLimit{Requests: 1, Period: time.Minute, Burst: 1}}
+
+	// Лимит не обращается к хранилищу ключей: у мока нет ожиданий
+	mockApiKeyRepo := new(mocks.MockApiKeyRepo)
+	h := newTestHandlers(Deps{Keys: services.NewApiKeyService(mockApiKeyRepo)})
+	router := gin.New()
+	router.Use(h.RateLimitMiddleware(realization.NewMemoryRateLimiter(), config))
+	router.POST("/api/auth", func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+
+	request := func(remoteAddr, apiKey string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/auth", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("Authorization", "ApiKey "+apiKey)
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// Случайные ключи не получают собственный лимит и учитываются по IP-адресу
+	assert.Equal(t, http.StatusOK, request("10.0.0.1:1234", "mk_random1_x"))
+	assert.Equal(t, http.StatusTooManyRequests, request("10.0.0.1:1234", "mk_random2_x"))
+	assert.Equal(t, http.StatusOK, request("10.0.0.2:1234", "mk_random3_x"))
+
+	mockApiKeyRepo.AssertNotCalled(t, "GetByHash", mock.Anything, mock.Anything)
+	mockApiKeyRepo.AssertNotCalled(t, "Touch", mock.Anything, mock.Anything)
+}
+
+func TestRequireRole(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockAuthRepo := new(mocks.MockAuthRepo)
+	h := newTestHandlers(Deps{
+		User: services.NewUserService(new(mocks.MockUserRepo), mockAuthRepo, new(mocks.MockUserInfoRepo), new(mocks.MockTwoFactorRepo), new(mocks.MockMetricsRepo), noop.NewTracerProvider()),
+	})
+
+	mockAuthRepo.On("DecodeToken", "admin").Return(&domain.AuthorizationToken{Id: 1, Role: domain.ROLE_ADMIN}, nil)
+	mockAuthRepo.On("DecodeToken", "auditor").Return(&domain.AuthorizationToken{Id: 2, Role: domain.ROLE_AUDITOR}, nil)
+	mockAuthRepo.On("DecodeToken", "user").Return(&domain.AuthorizationToken{Id: 3, Role: domain.ROLE_USER}, nil)
+
+	router := gin.New()
+	router.Use(func(ctx *gin.Context) {
+		ctx.Set("token", ctx.GetHeader("Authorization"))
+	})
+	router.GET("/admin", h.RequireRole(domain.ROLE_ADMIN), func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+	router.GET("/audit", h.RequireRole(domain.ROLE_ADMIN, domain.ROLE_AUDITOR), func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+
+	tests := []struct {
+		path   string
+		token  string
+		status int
+	}{
+		{"/admin", "admin", http.StatusOK},
+		{"/admin", "auditor", http.StatusForbidden},
+		{"/admin", "user", http.StatusForbidden},
+		{"/audit", "admin", http.StatusOK},
+		{"/audit", "auditor", http.StatusOK},
+		{"/audit", "user", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		req.Header.Set("Authorization", tt.token)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, tt.status, w.Code, "%s as %s", tt.path, tt.token)
+	}
+}
+
+func TestRequireScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	key := &domain.ApiKey{Prefix: "mk_test", Scopes: []string{domain.SCOPE_BALANCE_READ}}
+
+	h := newTestHandlers(Deps{})
+	router := gin.New()
+	router.Use(func(ctx *gin.Context) {
+		if ctx.GetHeader("Authorization") != "" {
+			ctx.Set("apiKey", key)
+		}
+	})
+	router.GET("/balance", h.RequireScope(domain.SCOPE_BALANCE_READ), func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+	router.POST("/grant", h.RequireScope(domain.SCOPE_COINS_GRANT), func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+
+	tests := []struct {
+		method string
+		path   string
+		apiKey bool
+		status int
+	}{
+		{http.MethodGet, "/balance", true, http.StatusOK},
+		{http.MethodPost, "/grant", true, http.StatusForbidden},
+		{http.MethodGet, "/balance", false, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		if tt.apiKey {
+			req.Header.Set("Authorization", "ApiKey mk_test_secret")
+		}
+		router.ServeHTTP(w, req)
+		assert.Equal(t, tt.status, w.Code, tt.path)
+	}
+}
+
+func TestLoggerMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	core, logs := observer.New(zapcore.DebugLevel)
+
+	h := NewHandlers(Deps{Logger: realization.NewZapLogger(zap.New(core))})
+	router := gin.New()
+	router.Use(h.LoggerMiddleware())
+	router.GET("/api/info", func(ctx *gin.Context) {
+		// Так пользователя добавляет AuthMiddleware
+		h.withLogFields(ctx, domain.Field("userId", uint(7)))
+		h.log(ctx).Debug("Handling request")
+		ctx.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/info", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	entries := logs.All()
+	require.Len(t, entries, 2)
+
+	handled := entries[0].ContextMap()
+	completed := entries[1].ContextMap()
+
+	assert.Equal(t, "Request completed", entries[1].Message)
+	assert.NotEmpty(t, completed["requestId"])
+	assert.Equal(t, handled["requestId"], completed["requestId"])
+	assert.Equal(t, uint64(7), completed["userId"])
+	assert.Equal(t, "/api/info", completed["path"])
+	assert.Equal(t, int64(http.StatusOK), completed["status"])
+}
+
+func TestLoggerMiddleware_RequestId(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	h := newTestHandlers(Deps{})
+	router := gin.New()
+	router.Use(h.LoggerMiddleware())
+	router.GET("/api/info", func(ctx *gin.Context) {
+		h.answerError(ctx, &e.DbQueryError{
+			Kind:  domain.ErrInternal,
+			Err:   "Db query error",
+			Cause: errors.New("db is down"),
+		})
+	})
+
+	request := func(requestId string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/info", nil)
+		if requestId != "" {
+			req.Header.Set(REQUEST_ID_HEADER, requestId)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	tests := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{"generated", "", false},
+		{"accepted", "lb-7f3a.2_b", true},
+		{"forged log line", "abc\ninjected", false},
+		{"too long", strings.Repeat("a", MAX_REQUEST_ID_LENGTH+1), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := request(tt.incoming)
+			assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+			requestId := w.Header().Get(REQUEST_ID_HEADER)
+			require.NotEmpty(t, requestId)
+			if tt.keep {
+				assert.Equal(t, tt.incoming, requestId)
+			} else {
+				assert.NotEqual(t, tt.incoming, requestId)
+			}
+
+			var body errorForm
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			assert.Equal(t, "internal", body.Code)
+			assert.Equal(t, STATUS_INTERNAL_SERVER, body.Message)
+			assert.Equal(t, requestId, body.RequestId)
+		})
+	}
+}
+
+func TestAnswerError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name    string
+		err     error
+		status  int
+		code    string
+		message string
+		details map[string]any
+	}{
+		{
+			name:    "validation",
+			err:     &e.InvalidRequest{Kind: domain.ErrInvalidInput, Err: "Invalid email", Details: map[string]any{"field": "username"}},
+			status:  http.StatusBadRequest,
+			code:    "invalid_input",
+			message: "Invalid email",
+			details: map[string]any{"field": "username"},
+		},
+		{
+			name:    "insufficient funds",
+			err:     &services.NoMoneyError{Kind: domain.ErrInsufficientFunds, Err: "Not enough coins to complete the transaction"},
+			status:  http.StatusBadRequest,
+			code:    "insufficient_funds",
+			message: "Not enough coins to complete the transaction",
+		},
+		{
+			name:    "wrapped sentinel",
+			err:     fmt.Errorf("loading key: %w", domain.ErrNotFound),
+			status:  http.StatusNotFound,
+			code:    "not_found",
+			message: STATUS_NOT_FOUND,
+		},
+		{
+			name:    "internal cause is hidden",
+			err:     &e.DbQueryError{Kind: domain.ErrInternal, Err: "Db query error", Cause: errors.New("password authentication failed")},
+			status:  http.StatusInternalServerError,
+			code:    "internal",
+			message: STATUS_INTERNAL_SERVER,
+		},
+		{
+			name:    "plain error",
+			err:     errors.New("unexpected"),
+			status:  http.StatusInternalServerError,
+			code:    "internal",
+			message: STATUS_INTERNAL_SERVER,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHandlers(Deps{})
+			w := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(w)
+			ctx.Request = httptest.NewRequest(http.MethodGet, "/api/info", nil)
+
+			h.answerError(ctx, tt.err)
+
+			assert.Equal(t, tt.status, w.Code)
+			assert.True(t, ctx.IsAborted())
+
+			var body errorForm
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			assert.Equal(t, tt.code, body.Code)
+			assert.Equal(t, tt.message, body.Message)
+			assert.Equal(t, tt.details, body.Details)
+		})
+	}
+}
+
+func TestRecoveryMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	core, logs := observer.New(zapcore.DebugLevel)
+
+	h := NewHandlers(Deps{Logger: realization.NewZapLogger(zap.New(core))})
+	router := gin.New()
+	router.Use(h.LoggerMiddleware(), h.RecoveryMiddleware())
+	router.GET("/api/info", func(ctx *gin.Context) {
+		var info *domain.UserInfo
+		ctx.JSON(http.StatusOK, info.Coins)
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/info", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	// Клиент получает стандартный ответ без текста паники
+	var body errorForm
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "internal", body.Code)
+	assert.Equal(t, STATUS_INTERNAL_SERVER, body.Message)
+	assert.NotContains(t, w.Body.String(), "nil pointer")
+
+	// В лог попадают паника со стеком и поля запроса
+	panics := logs.FilterMessage("Panic recovered").All()
+	require.Len(t, panics, 1)
+	fields := panics[0].ContextMap()
+	assert.Contains(t, fields["panic"], "nil pointer dereference")
+	assert.Contains(t, fields["stack"], "TestRecoveryMiddleware")
+	assert.Equal(t, body.RequestId, fields["requestId"])
+}
@@ -1,71 +1,222 @@
 package server
 
 import (
+	"context"
+	"errors"
+	"merch/internal/domain"
+	"merch/internal/interfaces"
 	"merch/internal/presentation/postgres"
 	"merch/internal/presentation/realization"
 	"merch/internal/services"
+	"net"
+	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel/trace"
 )
 
-// Переменные для доступа к сервисам
-var (
-	BuyService  *services.MoneyService
-	UserService *services.UserService
-	SecretKey   string
+// API_KEY_SCHEME - схема заголовка Authorization для авторизации по API-ключу
+const API_KEY_SCHEME = "ApiKey"
+
+// Параметры входа через внешнего провайдера
+const (
+	SSO_STATE_COOKIE = "sso_state"      // Cookie с state и nonce на время входа
+	SSO_STATE_TTL    = 10 * time.Minute // Сколько времени у пользователя есть на вход у провайдера
 )
 
+// TRACING_SERVICE - имя сервера в спанах запросов
+const TRACING_SERVICE = "merch"
+
+// READY_TIMEOUT - время на все проверки готовности
+const READY_TIMEOUT = 2 * time.Second
+
 // Константы http ответов
 const (
-	STATUS_UNAUTHORIZED    = "Authorization required"
-	STATUS_INTERNAL_SERVER = "Sorry, something went wrong, we are already solving the problem"
-	STATUS_BAD_REQUEST     = "Invalid data"
+	STATUS_UNAUTHORIZED      = "Authorization required"
+	STATUS_INTERNAL_SERVER   = "Sorry, something went wrong, we are already solving the problem"
+	STATUS_BAD_REQUEST       = "Invalid data"
+	STATUS_TOO_MANY_REQUESTS = "Too many requests, try again later"
+	STATUS_FORBIDDEN         = "Access denied"
+	STATUS_NOT_FOUND         = "Not found"
+	STATUS_NOT_ALLOWED       = "Method not allowed"
 )
 
+// Deps - зависимости сервера
+type Deps struct {
+	Money     *services.MoneyService
+	User      *services.UserService
+	Guard     *services.LoginGuardService
+	Keys      *services.ApiKeyService
+	TwoFactor *services.TwoFactorService
+	Identity  *services.IdentityService
+	Health    *services.HealthService
+	Limiter   interfaces.RateLimitRepo // Хранилище счетчиков ограничения частоты запросов, без него - в памяти процесса
+	Limits    RateLimitConfig          // Политики ограничения частоты запросов
+	Metrics   interfaces.MetricsRepo   // Метрики запросов, без них /metrics не регистрируется
+	Tracer    trace.TracerProvider     // Провайдер трассировки, без него спаны запросов не создаются
+	Logger    interfaces.LoggerRepo
+	Db        *postgres.DB  // Подключение к базе данных, закрывается после остановки сервера
+	Port      string        // Порт, на котором сервер принимает запросы
+	Drain     time.Duration // Сколько ждать завершения текущих запросов при остановке, 0 - DEFAULT_DRAIN_TIMEOUT
+}
+
+// DEFAULT_DRAIN_TIMEOUT - время ожидания завершения текущих запросов при остановке по умолчанию
+const DEFAULT_DRAIN_TIMEOUT = 10 * time.Second
+
 // Server определяет сервер с сервисами
 type Server struct {
-	srv *gin.Engine
+	srv    *gin.Engine
+	http   *http.Server
+	db     *postgres.DB
+	drain  time.Duration
+	logger interfaces.LoggerRepo
 }
 
-// NewServer создает новый экземпляр Server
-func NewServer() *Server {
+// NewServer создает новый экземпляр Server с указанными зависимостями.
+// В одном процессе можно создать несколько независимых серверов
+func NewServer(deps Deps) *Server {
 	gin.SetMode(gin.ReleaseMode)
 	srv := gin.New()
 
-	h := NewHandlers()
+	h := NewHandlers(deps)
+
+	if deps.Tracer != nil {
+		// Спан запроса продолжает трассу из заголовка traceparent, если клиент его передал
+		srv.Use(otelgin.Middleware(TRACING_SERVICE, otelgin.WithTracerProvider(deps.Tracer), otelgin.WithPropagators(realization.Propagator())))
+	}
+	srv.Use(h.LoggerMiddleware())
+	if deps.Metrics != nil {
+		srv.Use(h.MetricsMiddleware(deps.Metrics))
+		srv.GET("/metrics", gin.WrapH(deps.Metrics.Handler()))
+	}
+	// Паника в обработчике превращается в ответ 500 после логгера и метрик, чтобы запрос был учтен
+	srv.Use(h.RecoveryMiddleware())
+
+	// Проверки состояния и метрики не ограничиваются по частоте и не требуют авторизации
+	srv.GET("/healthz", h.Healthz)
+	srv.GET("/readyz", h.Readyz)
+
+	// Ограничение частоты и авторизация подключаются к группам, а не ко всему серверу,
+	// чтобы неизвестный адрес получал 404, а не 401
+	srv.HandleMethodNotAllowed = true
+	srv.NoRoute(h.NotFound)
+	srv.NoMethod(h.MethodNotAllowed)
+
+	limiter := deps.Limiter
+	if limiter == nil {
+		limiter = realization.NewMemoryRateLimiter()
+	}
 
-	srv.Use(LoggerMiddleware())
-	srv.POST("/api/auth", h.Auth)
+	api := srv.Group("/api", h.RateLimitMiddleware(limiter, deps.Limits))
+	api.POST("/auth", h.Auth)
+	api.POST("/auth/2fa", h.AuthSecondFactor)
+	api.GET("/auth/sso/:provider", h.SSOLogin)
+	api.GET("/auth/sso/:provider/callback", h.SSOCallback)
 
-	srv.Use(AuthMiddleware())
-	srv.GET("/api/info", h.GetInfo)
-	srv.POST("/api/sendCoin", h.SendCoin)
-	srv.GET("/api/buy/:item", h.BuyMerch)
+	authorized := api.Group("", h.AuthMiddleware())
+	authorized.GET("/info", h.GetInfo)
+	authorized.POST("/sendCoin", h.SendCoin)
+	authorized.GET("/buy/:item", h.BuyMerch)
+	authorized.POST("/2fa/enroll", h.TwoFactorEnroll)
+	authorized.POST("/2fa/confirm", h.TwoFactorConfirm)
+	authorized.POST("/2fa/disable", h.TwoFactorDisable)
+
+	admin := authorized.Group("/admin")
+	admin.GET("/users/:email", h.RequireRole(domain.ROLE_ADMIN, domain.ROLE_AUDITOR), h.GetUserInfo)
+	admin.PUT("/users/:email/role", h.RequireRole(domain.ROLE_ADMIN), h.SetRole)
+	admin.GET("/keys", h.RequireRole(domain.ROLE_ADMIN, domain.ROLE_AUDITOR), h.ListKeys)
+	admin.POST("/keys", h.RequireRole(domain.ROLE_ADMIN), h.IssueKey)
+	admin.DELETE("/keys/:id", h.RequireRole(domain.ROLE_ADMIN), h.RevokeKey)
+
+	service := authorized.Group("/service")
+	service.GET("/users/:email/balance", h.RequireScope(domain.SCOPE_BALANCE_READ), h.ServiceBalance)
+	service.POST("/grant", h.RequireScope(domain.SCOPE_COINS_GRANT), h.ServiceGrant)
+
+	drain := deps.Drain
+	if drain <= 0 {
+		drain = DEFAULT_DRAIN_TIMEOUT
+	}
 
-	realization.LoggerService.Info("Server has been created")
+	deps.Logger.Info("Server has been created")
 	return &Server{
 		srv: srv,
+		http: &http.Server{
+			Addr:              ":" + deps.Port,
+			Handler:           srv,
+			ReadHeaderTimeout: 10 * time.Second,
+		},
+		db:     deps.Db,
+		drain:  drain,
+		logger: deps.Logger,
 	}
 }
 
-// Start запускает сервер
-func (s *Server) Start(money *services.MoneyService, user *services.UserService, secret, port string) error {
-	BuyService = money
-	UserService = user
-	SecretKey = secret
+// Handler возвращает http.Handler сервера, например для тестов через httptest
+func (s *Server) Handler() http.Handler {
+	return s.srv
+}
 
-	realization.LoggerService.Debug("Starting server")
-	err := s.srv.Run(":" + port)
+// Run запускает сервер и останавливает его после отмены ctx, например по сигналу SIGINT или SIGTERM
+func (s *Server) Run(ctx context.Context) error {
+	errs := make(chan error, 1)
+	go func() {
+		errs <- s.Start()
+	}()
 
+	select {
+	case err := <-errs:
+		// Сервер не запустился, но подключение к базе данных все равно нужно закрыть
+		return errors.Join(err, s.Shutdown())
+	case <-ctx.Done():
+		s.logger.Info("Received shutdown signal")
+		return s.Shutdown()
+	}
+}
+
+// Start запускает сервер на указанном порту и блокируется до его остановки
+func (s *Server) Start() error {
+	listener, err := net.Listen("tcp", s.http.Addr)
 	if err != nil {
 		return err
 	}
 
-	realization.LoggerService.Info("Stopping server")
-	return nil
+	return s.Serve(listener)
+}
+
+// Serve принимает запросы на переданном listener и блокируется до остановки сервера
+func (s *Server) Serve(listener net.Listener) error {
+	s.logger.Info("Starting server", domain.Field("addr", listener.Addr().String()))
+	err := s.http.Serve(listener)
+
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+
+	return err
 }
 
-// Shutdown завершает работу сервера и закрывает подключение к базе данных
+// Shutdown перестает принимать новые соединения, ждет завершения текущих запросов,
+// а затем закрывает подключение к базе данных. Запросы, не успевшие завершиться за отведенное время, прерываются
 func (s *Server) Shutdown() error {
-	return postgres.DbService.CloseDB()
+	s.logger.Info("Stopping server")
+	ctx, cancel := context.WithTimeout(context.Background(), s.drain)
+	defer cancel()
+
+	err := s.http.Shutdown(ctx)
+	if err != nil {
+		s.logger.Error("Draining connections error", domain.Field("error", err.Error()))
+		err = errors.Join(err, s.http.Close())
+	}
+
+	if s.db != nil {
+		err = errors.Join(err, s.db.CloseDB())
+	}
+
+	if err == nil {
+		s.logger.Info("Server has been stopped")
+	}
+
+	return err
 }
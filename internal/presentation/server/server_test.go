@@ -0,0 +1,356 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"merch/internal/domain"
+	"merch/internal/presentation/postgres"
+	"merch/internal/presentation/realization"
+	"merch/internal/services"
+	"merch/test/mocks"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestNewServer_IndependentInstances(t *testing.T) {
+	newServer := func(burst int) *Server {
+		logger := new(mocks.LoggerRepo)
+		logger.On("Info", mock.Anything).Maybe()
+		logger.On("Info", mock.Anything, mock.Anything).Maybe()
+
+		return NewServer(Deps{
+			Limiter: realization.NewMemoryRateLimiter(),
+			Limits: RateLimitConfig{
+				Routes: map[string]domain.RateLimit{
+					"/api/auth": {Requests: 1, Period: time.Minute, Burst: burst},
+				},
+			},
+			Logger: logger,
+		})
+	}
+
+	strict := newServer(1)
+	loose := newServer(3)
+
+	request := func(srv *Server) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/auth", strings.NewReader("{"))
+		srv.Handler().ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// У каждого сервера свои зависимости, поэтому лимиты не влияют друг на друга
+	t.Run("strict", func(t *testing.T) {
+		t.Parallel()
+		assert.Equal(t, http.StatusBadRequest, request(strict))
+		assert.Equal(t, http.StatusTooManyRequests, request(strict))
+	})
+
+	t.Run("loose", func(t *testing.T) {
+		t.Parallel()
+		for i := 0; i < 3; i++ {
+			assert.Equal(t, http.StatusBadRequest, request(loose))
+		}
+		assert.Equal(t, http.StatusTooManyRequests, request(loose))
+	})
+}
+
+// newBlockingServer создает сервер, у которого перевод монет блокируется до закрытия release
+func newBlockingServer(t *testing.T, drain time.Duration) (*Server, sqlmock.Sqlmock, chan struct{}, chan struct{}) {
+	logger := new(mocks.LoggerRepo)
+	logger.On("Debug", mock.Anything).Maybe()
+	logger.On("Debug", mock.Anything, mock.Anything).Maybe()
+	logger.On("Info", mock.Anything).Maybe()
+	logger.On("Info", mock.Anything, mock.Anything).Maybe()
+	logger.On("Warn", mock.Anything).Maybe()
+	logger.On("Warn", mock.Anything, mock.Anything).Maybe()
+	logger.On("Error", mock.Anything).Maybe()
+	logger.On("Error", mock.Anything, mock.Anything).Maybe()
+
+	mockAuthRepo := new(mocks.MockAuthRepo)
+	mockUserRepo := new(mocks.MockUserRepo)
+	mockTransactionRepo := new(mocks.MockTransactionRepo)
+	mockInventoryRepo := new(mocks.MockInventoryRepo)
+	metrics := new(mocks.MockMetricsRepo)
+	metrics.On("CoinsTransferred", 10).Maybe()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	mockAuthRepo.On("DecodeToken", "token").Return(&domain.AuthorizationToken{Id: 1, Email: "sender@example.com"}, nil)
+	access := true
+	mockAuthRepo.On("Access", mock.Anything, "token", uint64(1)).Return(&access, nil)
+	mockUserRepo.On("GetByEmail", mock.Anything, "sender@example.com").Return(&domain.User{Id: 1, Email: "sender@example.com", Coins: 100}, nil)
+	mockTransactionRepo.On("Transfer", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		close(started)
+		<-release
+	}).Return(nil)
+
+	sqlDB, sqlMock, err := sqlmock.New()
+	require.NoError(t, err)
+	sqlMock.ExpectClose()
+
+	srv := NewServer(Deps{
+		Money:   services.NewMoneyService(mockUserRepo, mockTransactionRepo, mockInventoryRepo, metrics, noop.NewTracerProvider()),
+		User:    services.NewUserService(mockUserRepo, mockAuthRepo, new(mocks.MockUserInfoRepo), new(mocks.MockTwoFactorRepo), metrics, noop.NewTracerProvider()),
+		Limiter: realization.NewMemoryRateLimiter(),
+		Logger:  logger,
+		Db:      &postgres.DB{Db: sqlDB, Logger: logger},
+		Drain:   drain,
+	})
+
+	return srv, sqlMock, started, release
+}
+
+func TestServer_ShutdownDrainsInFlightRequests(t *testing.T) {
+	srv, sqlMock, started, release := newBlockingServer(t, time.Second)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	served := make(chan error, 1)
+	go func() {
+		served <- srv.Serve(listener)
+	}()
+
+	responses := make(chan int, 1)
+	go func() {
+		req, _ := http.NewRequest(http.MethodPost, "http://"+listener.Addr().String()+"/api/sendCoin", strings.NewReader(`{"toUser":"receiver@example.com","amount":10}`))
+		req.Header.Set("Authorization", "Bearer token")
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			responses <- 0
+			return
+		}
+		_ = resp.Body.Close()
+		responses <- resp.StatusCode
+	}()
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("request did not reach the transfer")
+	}
+
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- srv.Shutdown()
+	}()
+
+	// Пока перевод не завершен, сервер не должен останавливаться
+	select {
+	case <-stopped:
+		t.Fatal("server stopped before in-flight request completed")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+
+	assert.Equal(t, http.StatusOK, <-responses)
+	assert.NoError(t, <-stopped)
+	assert.NoError(t, <-served)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestServer_ShutdownDeadlineExceeded(t *testing.T) {
+	srv, sqlMock, started, release := newBlockingServer(t, 50*time.Millisecond)
+	defer close(release)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() {
+		_ = srv.Serve(listener)
+	}()
+
+	go func() {
+		req, _ := http.NewRequest(http.MethodPost, "http://"+listener.Addr().String()+"/api/sendCoin", strings.NewReader(`{"toUser":"receiver@example.com","amount":10}`))
+		req.Header.Set("Authorization", "Bearer token")
+
+		resp, err := http.DefaultClient.Do(req)
+		if err == nil {
+			_ = resp.Body.Close()
+		}
+	}()
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("request did not reach the transfer")
+	}
+
+	// Запрос не успевает завершиться, соединение прерывается, а база данных все равно закрывается
+	err = srv.Shutdown()
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
+
+func TestNewServer_HealthEndpoints(t *testing.T) {
+	logger := new(mocks.LoggerRepo)
+	logger.On("Info", mock.Anything).Maybe()
+	logger.On("Info", mock.Anything, mock.Anything).Maybe()
+	logger.On("Warn", mock.Anything).Maybe()
+	logger.On("Warn", mock.Anything, mock.Anything).Maybe()
+
+	mockHealthRepo := new(mocks.MockHealthRepo)
+	mockHealthRepo.On("Ping", mock.Anything).Return(nil)
+	mockHealthRepo.On("SchemaVersion", mock.Anything).Return(uint(7), false, nil).Once()
+	mockHealthRepo.On("SchemaVersion", mock.Anything).Return(uint(6), false, nil).Once()
+
+	// Даже при самом строгом лимите проверки состояния не ограничиваются и не требуют авторизации
+	srv := NewServer(Deps{
+		Health:  services.NewHealthService(mockHealthRepo, 7),
+		Limiter: realization.NewMemoryRateLimiter(),
+		Limits:  RateLimitConfig{Default: domain.RateLimit{Requests: 1, Period: time.Minute, Burst: 1}},
+		Logger:  logger,
+	})
+
+	request := func(path string) (int, domain.HealthReport) {
+		w := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+		var report domain.HealthReport
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+		return w.Code, report
+	}
+
+	code, report := request("/healthz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, domain.HEALTH_OK, report.Status)
+
+	code, report = request("/readyz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, domain.HEALTH_OK, report.Checks[services.CHECK_DATABASE].Status)
+	assert.Equal(t, domain.HEALTH_OK, report.Checks[services.CHECK_MIGRATIONS].Status)
+
+	code, report = request("/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, domain.HEALTH_FAIL, report.Status)
+	assert.Equal(t, "schema version is 6, expected 7", report.Checks[services.CHECK_MIGRATIONS].Error)
+}
+
+func TestNewServer_Metrics(t *testing.T) {
+	logger := new(mocks.LoggerRepo)
+	logger.On("Info", mock.Anything).Maybe()
+	logger.On("Info", mock.Anything, mock.Anything).Maybe()
+
+	metrics := new(mocks.MockMetricsRepo)
+	metrics.On("Handler").Return(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	metrics.On("ObserveRequest", http.MethodGet, "/healthz", http.StatusOK, mock.Anything).Once()
+	metrics.On("ObserveRequest", http.MethodGet, "/metrics", http.StatusOK, mock.Anything).Once()
+	// Для неизвестных адресов вместо шаблона маршрута используется общее значение
+	metrics.On("ObserveRequest", http.MethodGet, "unmatched", mock.Anything, mock.Anything).Once()
+
+	mockHealthRepo := new(mocks.MockHealthRepo)
+	srv := NewServer(Deps{
+		Health:  services.NewHealthService(mockHealthRepo, 7),
+		Limiter: realization.NewMemoryRateLimiter(),
+		Metrics: metrics,
+		Logger:  logger,
+	})
+
+	for _, path := range []string{"/healthz", "/metrics", "/unknown"} {
+		w := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	metrics.AssertExpectations(t)
+}
+
+func TestNewServer_TracePropagation(t *testing.T) {
+	logger := new(mocks.LoggerRepo)
+	logger.On("Info", mock.Anything).Maybe()
+	logger.On("Info", mock.Anything, mock.Anything).Maybe()
+
+	recorder := tracetest.NewSpanRecorder()
+	srv := NewServer(Deps{
+		Health:  services.NewHealthService(new(mocks.MockHealthRepo), 7),
+		Limiter: realization.NewMemoryRateLimiter(),
+		Tracer:  sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)),
+		Logger:  logger,
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	srv.Handler().ServeHTTP(w, req)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "/healthz", spans[0].Name())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[0].SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", spans[0].Parent().SpanID().String())
+}
+
+func TestNewServer_UnknownRoutes(t *testing.T) {
+	logger := new(mocks.LoggerRepo)
+	logger.On("Info", mock.Anything).Maybe()
+	logger.On("Info", mock.Anything, mock.Anything).Maybe()
+
+	srv := NewServer(Deps{
+		Limiter: realization.NewMemoryRateLimiter(),
+		Logger:  logger,
+	})
+
+	tests := []struct {
+		method string
+		path   string
+		status int
+		code   string
+	}{
+		// Неизвестный адрес не проходит через авторизацию
+		{http.MethodGet, "/api/unknown", http.StatusNotFound, "not_found"},
+		{http.MethodGet, "/favicon.ico", http.StatusNotFound, "not_found"},
+		{http.MethodDelete, "/api/info", http.StatusMethodNotAllowed, "method_not_allowed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			srv.Handler().ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+			assert.Equal(t, tt.status, w.Code)
+
+			var body errorForm
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			assert.Equal(t, tt.code, body.Code)
+			assert.NotEmpty(t, body.RequestId)
+		})
+	}
+}
+
+func TestNewServer_DefaultLimiter(t *testing.T) {
+	logger := new(mocks.LoggerRepo)
+	logger.On("Info", mock.Anything).Maybe()
+	logger.On("Info", mock.Anything, mock.Anything).Maybe()
+	logger.On("Warn", mock.Anything).Maybe()
+	logger.On("Warn", mock.Anything, mock.Anything).Maybe()
+
+	// Без хранилища в зависимостях лимиты считаются в памяти процесса, а не приводят к панике
+	srv := NewServer(Deps{
+		Limits: RateLimitConfig{Default: domain.RateLimit{Requests: 1, Period: time.Minute, Burst: 1}},
+		Logger: logger,
+	})
+
+	request := func() int {
+		w := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/info", nil))
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, request())
+	assert.Equal(t, http.StatusTooManyRequests, request())
+}
@@ -3,8 +3,8 @@ package server
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"merch/internal/domain"
 	e "merch/internal/presentation/customError"
-	"net/http"
 	"regexp"
 	"strings"
 	"unicode"
@@ -14,22 +14,25 @@ import (
 func ValidPass(pass string) (string, error) {
 	if !isValidLength(pass) {
 		return "", &e.InvalidPasswordFormat{
-			Code: http.StatusBadRequest,
-			Err:  "The password length must be from 3 to 30 characters inclusive",
+			Kind:    domain.ErrInvalidInput,
+			Err:     "The password length must be from 3 to 30 characters inclusive",
+			Details: map[string]any{"field": "password"},
 		}
 	}
 
 	if !containsUpperAndDigit(pass) {
 		return "", &e.InvalidPasswordFormat{
-			Code: http.StatusBadRequest,
-			Err:  "Password must contain at least 1 capital letter and 1 number",
+			Kind:    domain.ErrInvalidInput,
+			Err:     "Password must contain at least 1 capital letter and 1 number",
+			Details: map[string]any{"field": "password"},
 		}
 	}
 
 	if containsInvalidChars(pass) {
 		return "", &e.InvalidPasswordFormat{
-			Code: http.StatusBadRequest,
-			Err:  "The password must consist of letters of the Latin alphabet, numbers and symbols _!@#&*-",
+			Kind:    domain.ErrInvalidInput,
+			Err:     "The password must consist of letters of the Latin alphabet, numbers and symbols _!@#&*-",
+			Details: map[string]any{"field": "password"},
 		}
 	}
 
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"merch/internal/domain"
+	"merch/internal/interfaces"
+	"strings"
+	"time"
+)
+
+// API_KEY_PREFIX - префикс, по которому API-ключ отличается от других секретов
+const API_KEY_PREFIX = "mk_"
+
+// InvalidApiKey - ошибка неверного, отозванного или истекшего API-ключа
+type InvalidApiKey = domain.TypedError[invalidApiKeyError]
+
+type invalidApiKeyError struct{}
+
+// InvalidScope - ошибка неизвестного права доступа
+type InvalidScope = domain.TypedError[invalidScopeError]
+
+type invalidScopeError struct{}
+
+// ApiKeyService предоставляет методы для выдачи и проверки API-ключей
+type ApiKeyService struct {
+	keys interfaces.ApiKeyRepo
+}
+
+// NewApiKeyService создает новый экземпляр ApiKeyService
+func NewApiKeyService(keys interfaces.ApiKeyRepo) *ApiKeyService {
+	return &ApiKeyService{
+		keys: keys,
+	}
+}
+
+// Issue выпускает новый ключ. Сам ключ возвращается только один раз, в базе данных хранится его хэш
+func (s *ApiKeyService) Issue(ctx context.Context, name string, scopes []string, ttl time.Duration, createdBy domain.UserId) (string, *domain.ApiKey, error) {
+	if name == "" || len(scopes) == 0 {
+		return "", nil, &InvalidScope{
+			Kind: domain.ErrInvalidInput,
+			Err:  "Key name and at least one scope are required",
+		}
+	}
+
+	for _, scope := range scopes {
+		if !domain.IsValidScope(scope) {
+			return "", nil, &InvalidScope{
+				Kind: domain.ErrInvalidInput,
+				Err:  "Unknown scope " + scope,
+			}
+		}
+	}
+
+	prefix, err := randomHex(4)
+	if err != nil {
+		return "", nil, err
+	}
+
+	secret, err := randomHex(32)
+	if err != nil {
+		return "", nil, err
+	}
+
+	raw := API_KEY_PREFIX + prefix + "_" + secret
+	key := domain.ApiKey{
+		Name:      name,
+		Prefix:    API_KEY_PREFIX + prefix,
+		Hash:      HashApiKey(raw),
+		Scopes:    scopes,
+		CreatedBy: createdBy,
+		CreatedAt: time.Now(),
+	}
+
+	if ttl > 0 {
+		expiresAt := key.CreatedAt.Add(ttl)
+		key.ExpiresAt = &expiresAt
+	}
+
+	id, err := s.keys.Create(ctx, key)
+	if err != nil {
+		return "", nil, err
+	}
+
+	key.Id = *id
+	return raw, &key, nil
+}
+
+// Authenticate проверяет ключ и отмечает его использование
+func (s *ApiKeyService) Authenticate(ctx context.Context, raw string) (*domain.ApiKey, error) {
+	if !strings.HasPrefix(raw, API_KEY_PREFIX) {
+		return nil, &InvalidApiKey{
+			Kind: domain.ErrUnauthorized,
+			Err:  "Invalid API key",
+		}
+	}
+
+	key, err := s.keys.GetByHash(ctx, HashApiKey(raw))
+	if err != nil {
+		return nil, err
+	}
+
+	if key == nil || !key.Active(time.Now()) {
+		return nil, &InvalidApiKey{
+			Kind: domain.ErrUnauthorized,
+			Err:  "API key is invalid, revoked or expired",
+		}
+	}
+
+	err = s.keys.Touch(ctx, key.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// List возвращает все выданные ключи
+func (s *ApiKeyService) List(ctx context.Context) ([]domain.ApiKey, error) {
+	return s.keys.List(ctx)
+}
+
+// Revoke отзывает ключ
+func (s *ApiKeyService) Revoke(ctx context.Context, id uint64) error {
+	return s.keys.Revoke(ctx, id)
+}
+
+// HashApiKey возвращает SHA-256 хэш ключа
+func HashApiKey(raw string) string {
+	hash := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(hash[:])
+}
+
+// randomHex возвращает n случайных байт в шестнадцатеричном виде
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", &InvalidApiKey{
+			Kind: domain.ErrInternal,
+			Err:  "Generating random value error: " + err.Error(),
+		}
+	}
+
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"merch/internal/domain"
+	"merch/internal/interfaces"
+)
+
+// Имена проверок готовности
+const (
+	CHECK_DATABASE   = "database"
+	CHECK_MIGRATIONS = "migrations"
+)
+
+// HealthService проверяет, готов ли сервис принимать запросы
+type HealthService struct {
+	repo    interfaces.HealthRepo
+	version uint
+}
+
+// NewHealthService создает новый экземпляр HealthService, который ожидает схему базы данных версии version
+func NewHealthService(repo interfaces.HealthRepo, version uint) *HealthService {
+	return &HealthService{
+		repo:    repo,
+		version: version,
+	}
+}
+
+// Live сообщает, что процесс работает. Зависимости не проверяются, чтобы их сбой не приводил к перезапуску
+func (s *HealthService) Live() *domain.HealthReport {
+	return &domain.HealthReport{Status: domain.HEALTH_OK}
+}
+
+// Ready проверяет доступность базы данных и версию ее схемы
+func (s *HealthService) Ready(ctx context.Context) *domain.HealthReport {
+	report := &domain.HealthReport{
+		Status: domain.HEALTH_OK,
+		Checks: make(map[string]domain.HealthCheck, 2),
+	}
+
+	fail := func(name string, err error) {
+		report.Status = domain.HEALTH_FAIL
+		report.Checks[name] = domain.HealthCheck{Status: domain.HEALTH_FAIL, Error: err.Error()}
+	}
+
+	err := s.repo.Ping(ctx)
+	if err != nil {
+		fail(CHECK_DATABASE, err)
+		fail(CHECK_MIGRATIONS, fmt.Errorf("database is unavailable"))
+		return report
+	}
+	report.Checks[CHECK_DATABASE] = domain.HealthCheck{Status: domain.HEALTH_OK}
+
+	version, dirty, err := s.repo.SchemaVersion(ctx)
+	switch {
+	case err != nil:
+		fail(CHECK_MIGRATIONS, err)
+	case dirty:
+		fail(CHECK_MIGRATIONS, fmt.Errorf("migration %d is dirty", version))
+	case version != s.version:
+		fail(CHECK_MIGRATIONS, fmt.Errorf("schema version is %d, expected %d", version, s.version))
+	default:
+		report.Checks[CHECK_MIGRATIONS] = domain.HealthCheck{Status: domain.HEALTH_OK}
+	}
+
+	return report
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"context"
+	"merch/internal/domain"
+	"merch/internal/interfaces"
+)
+
+// UnknownProvider - ошибка неизвестного провайдера удостоверений
+type UnknownProvider = domain.TypedError[unknownProviderError]
+
+type unknownProviderError struct{}
+
+// UnverifiedIdentity - ошибка внешней учетной записи без подтвержденного email
+type UnverifiedIdentity = domain.TypedError[unverifiedIdentityError]
+
+type unverifiedIdentityError struct{}
+
+// IdentityService предоставляет методы для входа через внешние провайдеры удостоверений (SSO)
+type IdentityService struct {
+	providers map[string]interfaces.IdentityProvider
+	identity  interfaces.ExternalIdentityRepo
+	user      interfaces.UserRepo
+	auth      interfaces.AuthRepo
+	twoFactor interfaces.TwoFactorRepo
+}
+
+// NewIdentityService создает новый экземпляр IdentityService с указанными провайдерами
+func NewIdentityService(identity interfaces.ExternalIdentityRepo, user interfaces.UserRepo, auth interfaces.AuthRepo, twoFactor interfaces.TwoFactorRepo, providers ...interfaces.IdentityProvider) *IdentityService {
+	byName := make(map[string]interfaces.IdentityProvider, len(providers))
+	for _, provider := range providers {
+		byName[provider.Name()] = provider
+	}
+
+	return &IdentityService{
+		providers: byName,
+		identity:  identity,
+		user:      user,
+		auth:      auth,
+		twoFactor: twoFactor,
+	}
+}
+
+// Begin начинает вход через провайдера: создает state и nonce и возвращает адрес страницы входа
+func (s *IdentityService) Begin(name string) (*domain.ExternalLogin, error) {
+	provider, err := s.provider(name)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := randomHex(16)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := randomHex(16)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.ExternalLogin{
+		URL:   provider.AuthCodeURL(state, nonce),
+		State: state,
+		Nonce: nonce,
+	}, nil
+}
+
+// Complete завершает вход: обменивает код у провайдера и находит связанного пользователя.
+// Учетная запись связывается с пользователем по подтвержденному email, новый пользователь получает START_MONEY
+func (s *IdentityService) Complete(ctx context.Context, name, code, nonce string) (*domain.LoginResult, error) {
+	provider, err := s.provider(name)
+	if err != nil {
+		return nil, err
+	}
+
+	external, err := provider.Exchange(ctx, code, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	userId, err := s.identity.GetUserId(ctx, external.Provider, external.Subject)
+	if err != nil {
+		return nil, err
+	}
+
+	var user *domain.User
+	if userId != nil {
+		user, err = s.user.GetById(ctx, *userId)
+	} else {
+		user, err = s.provision(ctx, external)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return startSession(ctx, s.auth, s.twoFactor, domain.AuthorizationData{
+		Id:       user.Id,
+		Username: user.Email,
+		Role:     user.Role,
+	})
+}
+
+// provision находит пользователя по email или создает нового и связывает его с внешней учетной записью
+func (s *IdentityService) provision(ctx context.Context, external *domain.ExternalIdentity) (*domain.User, error) {
+	if external.Email == "" || !external.EmailVerified {
+		return nil, &UnverifiedIdentity{
+			Kind: domain.ErrUnauthorized,
+			Err:  "Identity provider did not return a verified email",
+		}
+	}
+
+	user, err := s.user.GetByEmail(ctx, external.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	if user == nil {
+		// Пароль случайный, поэтому войти по паролю такой пользователь не сможет
+		password, err := randomHex(32)
+		if err != nil {
+			return nil, err
+		}
+
+		user = domain.CreateUser(external.Email, password, START_MONEY)
+		id, err := s.user.Create(ctx, *user)
+		if err != nil {
+			return nil, err
+		}
+
+		user.Id = *id
+	}
+
+	err = s.identity.Link(ctx, external.Provider, external.Subject, user.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// provider возвращает провайдера по названию
+func (s *IdentityService) provider(name string) (interfaces.IdentityProvider, error) {
+	provider, exists := s.providers[name]
+	if !exists {
+		return nil, &UnknownProvider{
+			Kind: domain.ErrNotFound,
+			Err:  "Unknown identity provider " + name,
+		}
+	}
+
+	return provider, nil
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"merch/internal/interfaces"
+	"strings"
+	"time"
+)
+
+// Параметры защиты от перебора паролей
+const (
+	MAX_ACCOUNT_FAILURES = 5              // Количество неудачных попыток для аккаунта до блокировки
+	MAX_IP_FAILURES      = 20             // Количество неудачных попыток с одного IP-адреса до блокировки
+	LOCKOUT_BASE         = time.Minute    // Длительность первой блокировки
+	LOCKOUT_MAX          = time.Hour      // Максимальная длительность блокировки
+	FAILURES_WINDOW      = 24 * time.Hour // Через сколько после последней неудачи счетчик начинается заново
+)
+
+// LoginGuardService защищает вход от перебора паролей с помощью счетчиков по аккаунту и IP-адресу
+type LoginGuardService struct {
+	attempts interfaces.LoginAttemptRepo
+}
+
+// NewLoginGuardService создает новый экземпляр LoginGuardService
+func NewLoginGuardService(attempts interfaces.LoginAttemptRepo) *LoginGuardService {
+	return &LoginGuardService{
+		attempts: attempts,
+	}
+}
+
+// Check возвращает время, через которое можно повторить попытку входа, или 0, если вход разрешен
+func (s *LoginGuardService) Check(ctx context.Context, email, ip string) (time.Duration, error) {
+	var retryAfter time.Duration
+
+	for _, key := range []string{accountKey(email), ipKey(ip)} {
+		attempt, err := s.attempts.Get(ctx, key)
+		if err != nil {
+			return 0, err
+		}
+
+		if attempt != nil && attempt.RetryAfter > retryAfter {
+			retryAfter = attempt.RetryAfter
+		}
+	}
+
+	return retryAfter, nil
+}
+
+// Fail регистрирует неудачную попытку входа и возвращает длительность блокировки, если она была наложена
+func (s *LoginGuardService) Fail(ctx context.Context, email, ip string) (time.Duration, error) {
+	var retryAfter time.Duration
+
+	limits := map[string]int{
+		accountKey(email): MAX_ACCOUNT_FAILURES,
+		ipKey(ip):         MAX_IP_FAILURES,
+	}
+
+	for key, limit := range limits {
+		failures, err := s.attempts.Fail(ctx, key, FAILURES_WINDOW)
+		if err != nil {
+			return 0, err
+		}
+
+		lockout := Lockout(failures, limit)
+		if lockout == 0 {
+			continue
+		}
+
+		err = s.attempts.Lock(ctx, key, lockout)
+		if err != nil {
+			return 0, err
+		}
+
+		if lockout > retryAfter {
+			retryAfter = lockout
+		}
+	}
+
+	return retryAfter, nil
+}
+
+// Success сбрасывает счетчик аккаунта после успешного входа.
+// Счетчик IP-адреса не сбрасывается, иначе вход в собственный аккаунт позволял бы продолжать перебор чужих
+func (s *LoginGuardService) Success(ctx context.Context, email string) error {
+	return s.attempts.Reset(ctx, accountKey(email))
+}
+
+// Lockout вычисляет длительность блокировки: она удваивается с каждой неудачей сверх лимита
+func Lockout(failures, limit int) time.Duration {
+	if failures < limit {
+		return 0
+	}
+
+	lockout := LOCKOUT_BASE
+	for i := limit; i < failures; i++ {
+		lockout *= 2
+		if lockout >= LOCKOUT_MAX {
+			return LOCKOUT_MAX
+		}
+	}
+
+	return lockout
+}
+
+func accountKey(email string) string {
+	return "email:" + strings.ToLower(email)
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
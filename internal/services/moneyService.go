@@ -1,40 +1,60 @@
 package services
 
 import (
+	"context"
 	"merch/internal/domain"
 	"merch/internal/interfaces"
-	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // NoMoneyError используется для обозначения ошибки недостатка средств
-type NoMoneyError = domain.BaseError
+type NoMoneyError = domain.TypedError[noMoneyError]
+
+type noMoneyError struct{}
+
+// InvalidAmount используется для обозначения неверной суммы
+type InvalidAmount = domain.TypedError[invalidAmountError]
+
+type invalidAmountError struct{}
+
+// MAX_GRANT_AMOUNT - наибольшая сумма одного начисления монет интеграцией
+const MAX_GRANT_AMOUNT = 10000
 
 // MoneyService предоставляет методы для работы с покупками и переводами средств
 type MoneyService struct {
 	user        interfaces.UserRepo
 	transaction interfaces.TransactionRepo
 	inventory   interfaces.InventoryRepo
+	metrics     interfaces.MetricsRepo
+	tracer      trace.Tracer
 }
 
 // NewMoneyService создает новый экземпляр MoneyService
-func NewMoneyService(user interfaces.UserRepo, transaction interfaces.TransactionRepo, inventory interfaces.InventoryRepo) *MoneyService {
+func NewMoneyService(user interfaces.UserRepo, transaction interfaces.TransactionRepo, inventory interfaces.InventoryRepo, metrics interfaces.MetricsRepo, tracer trace.TracerProvider) *MoneyService {
 	return &MoneyService{
 		user:        user,
 		transaction: transaction,
 		inventory:   inventory,
+		metrics:     metrics,
+		tracer:      tracer.Tracer(TRACER_NAME),
 	}
 }
 
 // BuyMerch выполняет покупку мерча
-func (s *MoneyService) BuyMerch(inventory domain.Inventory) error {
+func (s *MoneyService) BuyMerch(ctx context.Context, inventory domain.Inventory) (err error) {
+	ctx, span := s.tracer.Start(ctx, "MoneyService.BuyMerch", trace.WithAttributes(attribute.String("merch.item", inventory.Subject)))
+	defer func() { endSpan(span, err) }()
+
 	// Получение предмета по названию
-	subject, err := s.inventory.GetSubjectByName(inventory.Subject)
+	subject, err := s.inventory.GetSubjectByName(ctx, inventory.Subject)
 	if err != nil {
 		return err
 	}
 
 	// Получение данных пользователя по его идентификатору
-	user, err := s.user.GetById(inventory.UserId)
+	user, err := s.user.GetById(ctx, inventory.UserId)
 	if err != nil {
 		return err
 	}
@@ -42,24 +62,28 @@ func (s *MoneyService) BuyMerch(inventory domain.Inventory) error {
 	// Проверка, хватает ли пользователю монет для покупки
 	if user.Coins < subject.Cost {
 		return &NoMoneyError{
-			Code: http.StatusBadRequest,
+			Kind: domain.ErrInsufficientFunds,
 			Err:  "Not enough coins to complete the transaction",
 		}
 	}
 
 	// Покупка предмета
-	err = s.inventory.Buy(inventory.UserId, *subject)
+	err = s.inventory.Buy(ctx, inventory.UserId, *subject)
 	if err != nil {
 		return err
 	}
 
+	s.metrics.ItemPurchased(subject.Name)
 	return nil
 }
 
 // MoneyTransfer выполняет перевод средств
-func (s *MoneyService) MoneyTransfer(transaction domain.Transaction) error {
+func (s *MoneyService) MoneyTransfer(ctx context.Context, transaction domain.Transaction) (err error) {
+	ctx, span := s.tracer.Start(ctx, "MoneyService.MoneyTransfer", trace.WithAttributes(attribute.Int("merch.amount", transaction.Amount)))
+	defer func() { endSpan(span, err) }()
+
 	// Получение данных пользователя по его email (имя отправителя)
-	user, err := s.user.GetByEmail(transaction.SenderName)
+	user, err := s.user.GetByEmail(ctx, transaction.SenderName)
 	if err != nil {
 		return err
 	}
@@ -67,11 +91,41 @@ func (s *MoneyService) MoneyTransfer(transaction domain.Transaction) error {
 	// Проверка, хватает ли пользователю монет для перевода
 	if user.Coins < transaction.Amount {
 		return &NoMoneyError{
-			Code: http.StatusBadRequest,
+			Kind: domain.ErrInsufficientFunds,
 			Err:  "Not enough coins to complete the transaction",
 		}
 	}
 
 	// Выполнение перевода средств
-	return s.transaction.Transfer(transaction)
+	err = s.transaction.Transfer(ctx, transaction)
+	if err != nil {
+		return err
+	}
+
+	s.metrics.CoinsTransferred(transaction.Amount)
+	return nil
+}
+
+// Grant начисляет монеты пользователю от имени интеграции. Начисление сохраняется вместе с API-ключом,
+// которым оно выполнено, и попадает в историю полученных монет пользователя
+func (s *MoneyService) Grant(ctx context.Context, grant domain.Grant) (err error) {
+	ctx, span := s.tracer.Start(ctx, "MoneyService.Grant", trace.WithAttributes(attribute.Int("merch.amount", grant.Amount)))
+	defer func() { endSpan(span, err) }()
+
+	if grant.Amount <= 0 {
+		return &InvalidAmount{
+			Kind: domain.ErrInvalidInput,
+			Err:  "Amount must be positive",
+		}
+	}
+
+	if grant.Amount > MAX_GRANT_AMOUNT {
+		return &InvalidAmount{
+			Kind:    domain.ErrInvalidInput,
+			Err:     "Amount exceeds the grant limit",
+			Details: map[string]any{"maxAmount": MAX_GRANT_AMOUNT},
+		}
+	}
+
+	return s.user.AddCoins(ctx, grant)
 }
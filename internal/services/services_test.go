@@ -1,22 +1,32 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"merch/internal/domain"
 	"merch/test/mocks"
-	"net/http"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
 )
 
 var (
 	mockUserRepo        *mocks.MockUserRepo
 	mockTransactionRepo *mocks.MockTransactionRepo
 	mockInventoryRepo   *mocks.MockInventoryRepo
+	mockUserInfoRepo    *mocks.MockUserInfoRepo
 	mockAuthRepo        *mocks.MockAuthRepo
+	mockTwoFactorRepo   *mocks.MockTwoFactorRepo
+	mockMetricsRepo     *mocks.MockMetricsRepo
 	moneyService        *MoneyService
 	userService         *UserService
 )
@@ -25,10 +35,16 @@ func setup() {
 	mockUserRepo = new(mocks.MockUserRepo)
 	mockTransactionRepo = new(mocks.MockTransactionRepo)
 	mockInventoryRepo = new(mocks.MockInventoryRepo)
+	mockUserInfoRepo = new(mocks.MockUserInfoRepo)
 	mockAuthRepo = new(mocks.MockAuthRepo)
-
-	moneyService = NewMoneyService(mockUserRepo, mockTransactionRepo, mockInventoryRepo)
-	userService = NewUserService(mockUserRepo, mockAuthRepo, mockTransactionRepo, mockInventoryRepo)
+	mockTwoFactorRepo = new(mocks.MockTwoFactorRepo)
+	mockMetricsRepo = new(mocks.MockMetricsRepo)
+	mockMetricsRepo.On("CoinsTransferred", mock.Anything).Maybe()
+	mockMetricsRepo.On("ItemPurchased", mock.Anything).Maybe()
+	mockMetricsRepo.On("LoginFailed", mock.Anything).Maybe()
+
+	moneyService = NewMoneyService(mockUserRepo, mockTransactionRepo, mockInventoryRepo, mockMetricsRepo, noop.NewTracerProvider())
+	userService = NewUserService(mockUserRepo, mockAuthRepo, mockUserInfoRepo, mockTwoFactorRepo, mockMetricsRepo, noop.NewTracerProvider())
 }
 
 type InvalidSubjectName = NoMoneyError
@@ -69,7 +85,7 @@ func TestMoneyService_BuyMerch(t *testing.T) {
 			user:                &domain.User{Id: 1, Email: "test@example.com", Coins: 50},
 			subject:             nil,
 			inventory:           domain.Inventory{Subject: "cup", UserId: 1},
-			getSubjectByNameErr: &InvalidSubjectName{Code: http.StatusBadRequest},
+			getSubjectByNameErr: &InvalidSubjectName{Kind: domain.ErrInvalidInput},
 			expectErr:           true,
 			errType:             &InvalidSubjectName{},
 		},
@@ -97,18 +113,18 @@ func TestMoneyService_BuyMerch(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			mockInventoryRepo.On("GetSubjectByName", tt.inventory.Subject).Return(tt.subject, tt.getSubjectByNameErr)
-			mockUserRepo.On("GetById", tt.inventory.UserId).Return(tt.user, tt.getByIdErr)
+			mockInventoryRepo.On("GetSubjectByName", mock.Anything, tt.inventory.Subject).Return(tt.subject, tt.getSubjectByNameErr)
+			mockUserRepo.On("GetById", mock.Anything, tt.inventory.UserId).Return(tt.user, tt.getByIdErr)
 			if tt.subject != nil && tt.user != nil {
-				mockInventoryRepo.On("Buy", tt.inventory.UserId, *tt.subject).Return(tt.buyErr)
+				mockInventoryRepo.On("Buy", mock.Anything, tt.inventory.UserId, *tt.subject).Return(tt.buyErr)
 			}
 
-			err := moneyService.BuyMerch(tt.inventory)
+			err := moneyService.BuyMerch(context.Background(), tt.inventory)
 			if tt.expectErr {
 				assert.Error(t, err)
 				assert.IsType(t, tt.errType, err)
-				if errType, ok := err.(*NoMoneyError); ok {
-					assert.Equal(t, http.StatusBadRequest, errType.GetCode())
+				if _, ok := err.(*NoMoneyError); ok && tt.getSubjectByNameErr == nil {
+					assert.ErrorIs(t, err, domain.ErrInsufficientFunds)
 				}
 			} else {
 				assert.NoError(t, err)
@@ -159,12 +175,12 @@ func TestMoneyService_MoneyTransfer(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			mockUserRepo.On("GetByEmail", tt.transaction.SenderName).Return(tt.user, tt.getByEmailErr)
+			mockUserRepo.On("GetByEmail", mock.Anything, tt.transaction.SenderName).Return(tt.user, tt.getByEmailErr)
 			if !tt.expectErr {
-				mockTransactionRepo.On("Transfer", tt.transaction).Return(nil)
+				mockTransactionRepo.On("Transfer", mock.Anything, tt.transaction).Return(nil)
 			}
 
-			err := moneyService.MoneyTransfer(tt.transaction)
+			err := moneyService.MoneyTransfer(context.Background(), tt.transaction)
 			if tt.expectErr {
 				assert.Error(t, err)
 				assert.IsType(t, tt.errType, err)
@@ -241,25 +257,26 @@ func TestUserService_Login(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			mockUserRepo.On("GetByEmail", tt.authData.Username).Return(tt.user, tt.getByEmailErr)
+			mockUserRepo.On("GetByEmail", mock.Anything, tt.authData.Username).Return(tt.user, tt.getByEmailErr)
 			if tt.user == nil && tt.getByEmailErr == nil {
-				mockUserRepo.On("Create", mock.Anything).Return(&tt.expectedId, tt.createErr)
+				mockUserRepo.On("Create", mock.Anything, mock.Anything).Return(&tt.expectedId, tt.createErr)
 			}
 			if tt.createErr == nil {
-				mockAuthRepo.On("CreateToken", mock.Anything).Return(&tt.expectedToken, tt.createTokenErr)
+				mockTwoFactorRepo.On("Get", mock.Anything, mock.Anything).Return((*domain.TwoFactor)(nil), nil)
+				mockAuthRepo.On("CreateToken", mock.Anything, mock.Anything).Return(&tt.expectedToken, tt.createTokenErr)
 			}
 
-			token, err := userService.Login(tt.authData)
+			token, err := userService.Login(context.Background(), tt.authData)
 			if tt.expectErr {
 				assert.Error(t, err)
 				assert.Nil(t, token)
 				assert.IsType(t, tt.errType, err)
-				if errType, ok := err.(*InvalidPassword); ok {
-					assert.Equal(t, http.StatusUnauthorized, errType.GetCode())
+				if _, ok := err.(*InvalidPassword); ok {
+					assert.ErrorIs(t, err, domain.ErrUnauthorized)
 				}
 			} else {
 				assert.NoError(t, err)
-				assert.Equal(t, &tt.expectedToken, token)
+				assert.Equal(t, &domain.LoginResult{Token: tt.expectedToken}, token)
 			}
 
 			mockUserRepo.AssertExpectations(t)
@@ -275,78 +292,40 @@ func TestUserService_GetInfo(t *testing.T) {
 	setup()
 
 	tests := []struct {
-		name              string
-		user              *domain.User
-		transactions      *[]domain.Transaction
-		inventory         *[]domain.Inventory
-		getByIdErr        error
-		getTransactionErr error
-		getInventoryErr   error
-		expectErr         bool
-		errType           interface{}
+		name       string
+		info       *domain.UserInfo
+		getInfoErr error
 	}{
 		{
-			name:         "Successful GetInfo",
-			user:         &domain.User{Id: 1, Email: "testuser@example.com", Coins: 100},
-			transactions: &[]domain.Transaction{},
-			inventory:    &[]domain.Inventory{},
-			expectErr:    false,
-		},
-		{
-			name:         "GetById Error",
-			user:         nil,
-			transactions: &[]domain.Transaction{},
-			inventory:    &[]domain.Inventory{},
-			getByIdErr:   errors.New("user not found"),
-			expectErr:    true,
-			errType:      errors.New("user not found"),
+			name: "Successful GetInfo",
+			info: &domain.UserInfo{
+				Coins:     100,
+				Inventory: []domain.ItemQuantity{{Type: "cup", Quantity: 2}},
+				Received:  []domain.CoinTransfer{{User: "sender@example.com", Amount: 10}},
+				Sent:      []domain.CoinTransfer{},
+			},
 		},
 		{
-			name:              "GetTransaction Error",
-			user:              &domain.User{Id: 1, Email: "testuser@example.com", Coins: 100},
-			transactions:      nil,
-			inventory:         &[]domain.Inventory{},
-			getTransactionErr: errors.New("transaction error"),
-			expectErr:         true,
-			errType:           errors.New("transaction error"),
-		},
-		{
-			name:            "GetInventory Error",
-			user:            &domain.User{Id: 1, Email: "testuser@example.com", Coins: 100},
-			transactions:    &[]domain.Transaction{},
-			inventory:       nil,
-			getInventoryErr: errors.New("inventory error"),
-			expectErr:       true,
-			errType:         errors.New("inventory error"),
+			name:       "GetInfo Error",
+			info:       nil,
+			getInfoErr: errors.New("user not found"),
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			mockUserRepo.On("GetById", mock.Anything).Return(tt.user, tt.getByIdErr)
-			mockTransactionRepo.On("GetTransaction", mock.Anything).Return(tt.transactions, tt.getTransactionErr)
-			mockInventoryRepo.On("GetInventory", mock.Anything).Return(tt.inventory, tt.getInventoryErr)
+			mockUserInfoRepo.On("GetInfo", mock.Anything, uint64(1)).Return(tt.info, tt.getInfoErr).Once()
 
-			userInfo, err := userService.GetInfo(uint64(1))
-			if tt.expectErr {
-				assert.Error(t, err)
+			userInfo, err := userService.GetInfo(context.Background(), uint64(1))
+			if tt.getInfoErr != nil {
+				assert.ErrorIs(t, err, tt.getInfoErr)
 				assert.Nil(t, userInfo)
-				assert.IsType(t, tt.errType, err)
 			} else {
 				assert.NoError(t, err)
-				assert.NotNil(t, userInfo)
-				assert.Equal(t, tt.user.Coins, userInfo.Coins)
-				assert.Equal(t, tt.transactions, &userInfo.Transactions)
-				assert.Equal(t, tt.inventory, &userInfo.Inventory)
+				assert.Equal(t, tt.info, userInfo)
 			}
 
-			mockUserRepo.AssertExpectations(t)
-			mockTransactionRepo.AssertExpectations(t)
-			mockInventoryRepo.AssertExpectations(t)
-
-			mockUserRepo.ExpectedCalls = nil
-			mockTransactionRepo.ExpectedCalls = nil
-			mockInventoryRepo.ExpectedCalls = nil
+			mockUserInfoRepo.AssertExpectations(t)
 		})
 	}
 }
@@ -373,11 +352,543 @@ func TestUserService_Access(t *testing.T) {
 	userId := domain.UserId(1)
 	var expectedAccess domain.SuccessfulAuth = true
 
-	mockAuthRepo.On("Access", token, userId).Return(&expectedAccess, nil)
+	mockAuthRepo.On("Access", mock.Anything, token, userId).Return(&expectedAccess, nil)
 
-	access, err := userService.Access(token, userId)
+	access, err := userService.Access(context.Background(), token, userId)
 	assert.NoError(t, err)
 	assert.Equal(t, &expectedAccess, access)
 
 	mockAuthRepo.AssertExpectations(t)
 }
+
+func TestLockout(t *testing.T) {
+	assert.Equal(t, time.Duration(0), Lockout(4, 5))
+	assert.Equal(t, LOCKOUT_BASE, Lockout(5, 5))
+	assert.Equal(t, 2*LOCKOUT_BASE, Lockout(6, 5))
+	assert.Equal(t, 4*LOCKOUT_BASE, Lockout(7, 5))
+	assert.Equal(t, LOCKOUT_MAX, Lockout(100, 5))
+}
+
+func TestLoginGuardService_Check(t *testing.T) {
+	mockAttemptRepo := new(mocks.MockLoginAttemptRepo)
+	guard := NewLoginGuardService(mockAttemptRepo)
+
+	mockAttemptRepo.On("Get", mock.Anything, "email:user@example.com").Return(&domain.LoginAttempt{Failures: 5, RetryAfter: time.Minute}, nil)
+	mockAttemptRepo.On("Get", mock.Anything, "ip:127.0.0.1").Return((*domain.LoginAttempt)(nil), nil)
+
+	retryAfter, err := guard.Check(context.Background(), "User@Example.com", "127.0.0.1")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Minute, retryAfter)
+
+	mockAttemptRepo.AssertExpectations(t)
+}
+
+func TestLoginGuardService_Fail(t *testing.T) {
+	mockAttemptRepo := new(mocks.MockLoginAttemptRepo)
+	guard := NewLoginGuardService(mockAttemptRepo)
+
+	mockAttemptRepo.On("Fail", mock.Anything, "email:user@example.com", FAILURES_WINDOW).Return(MAX_ACCOUNT_FAILURES+1, nil)
+	mockAttemptRepo.On("Fail", mock.Anything, "ip:127.0.0.1", FAILURES_WINDOW).Return(1, nil)
+	mockAttemptRepo.On("Lock", mock.Anything, "email:user@example.com", 2*LOCKOUT_BASE).Return(nil)
+
+	retryAfter, err := guard.Fail(context.Background(), "user@example.com", "127.0.0.1")
+	assert.NoError(t, err)
+	assert.Equal(t, 2*LOCKOUT_BASE, retryAfter)
+
+	mockAttemptRepo.AssertExpectations(t)
+}
+
+func TestLoginGuardService_Success(t *testing.T) {
+	mockAttemptRepo := new(mocks.MockLoginAttemptRepo)
+	guard := NewLoginGuardService(mockAttemptRepo)
+
+	mockAttemptRepo.On("Reset", mock.Anything, "email:user@example.com").Return(nil)
+
+	err := guard.Success(context.Background(), "user@example.com")
+	assert.NoError(t, err)
+
+	mockAttemptRepo.AssertExpectations(t)
+}
+
+func TestUserService_SetRole(t *testing.T) {
+	setup()
+
+	tests := []struct {
+		name          string
+		email         domain.UserEmail
+		role          domain.Role
+		user          *domain.User
+		setRoleErr    error
+		expectSetRole bool
+		expectErr     bool
+	}{
+		{
+			name:          "Successful SetRole",
+			email:         "user@example.com",
+			role:          domain.ROLE_ADMIN,
+			user:          &domain.User{Id: 1, Email: "user@example.com", Role: domain.ROLE_USER},
+			expectSetRole: true,
+		},
+		{
+			name:      "Unknown Role",
+			email:     "user@example.com",
+			role:      "superuser",
+			expectErr: true,
+		},
+		{
+			name:      "User Not Found",
+			email:     "missing@example.com",
+			role:      domain.ROLE_AUDITOR,
+			user:      nil,
+			expectErr: true,
+		},
+		{
+			name:          "SetRole Error",
+			email:         "user@example.com",
+			role:          domain.ROLE_AUDITOR,
+			user:          &domain.User{Id: 1, Email: "user@example.com", Role: domain.ROLE_USER},
+			setRoleErr:    errors.New("database error"),
+			expectSetRole: true,
+			expectErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if domain.IsValidRole(tt.role) {
+				mockUserRepo.On("GetByEmail", mock.Anything, tt.email).Return(tt.user, nil)
+			}
+			if tt.expectSetRole {
+				mockUserRepo.On("SetRole", mock.Anything, tt.email, tt.role).Return(tt.setRoleErr)
+			}
+			if tt.expectSetRole && tt.setRoleErr == nil {
+				mockAuthRepo.On("Revoke", mock.Anything, tt.user.Id).Return(nil)
+			}
+
+			err := userService.SetRole(context.Background(), tt.email, tt.role)
+			if tt.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			mockUserRepo.AssertExpectations(t)
+			mockAuthRepo.AssertExpectations(t)
+
+			mockUserRepo.ExpectedCalls = nil
+			mockAuthRepo.ExpectedCalls = nil
+		})
+	}
+}
+
+func TestMoneyService_Grant(t *testing.T) {
+	setup()
+
+	grant := domain.Grant{ApiKeyId: 1, ReceiverName: "user@example.com", Amount: 100}
+	mockUserRepo.On("AddCoins", mock.Anything, grant).Return(nil)
+
+	err := moneyService.Grant(context.Background(), grant)
+	assert.NoError(t, err)
+
+	for _, amount := range []int{-100, 0, MAX_GRANT_AMOUNT + 1} {
+		err = moneyService.Grant(context.Background(), domain.Grant{ApiKeyId: 1, ReceiverName: "user@example.com", Amount: amount})
+		assert.ErrorIs(t, err, domain.ErrInvalidInput, "amount %d", amount)
+		assert.IsType(t, &InvalidAmount{}, err)
+	}
+
+	// Типы ошибок различаются через errors.As, общие поля доступны через domain.BaseError
+	var invalidAmount *InvalidAmount
+	var noMoney *NoMoneyError
+	var baseErr *domain.BaseError
+	assert.ErrorAs(t, err, &invalidAmount)
+	assert.False(t, errors.As(err, &noMoney))
+	require.ErrorAs(t, err, &baseErr)
+	assert.Equal(t, MAX_GRANT_AMOUNT, baseErr.Details["maxAmount"])
+
+	mockUserRepo.AssertExpectations(t)
+}
+
+func TestApiKeyService_Issue(t *testing.T) {
+	mockApiKeyRepo := new(mocks.MockApiKeyRepo)
+	keyService := NewApiKeyService(mockApiKeyRepo)
+	id := uint64(7)
+
+	var stored domain.ApiKey
+	mockApiKeyRepo.On("Create", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		stored = args.Get(1).(domain.ApiKey)
+	}).Return(&id, nil)
+
+	raw, key, err := keyService.Issue(context.Background(), "hr-bot", []string{domain.SCOPE_COINS_GRANT}, time.Hour, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, id, key.Id)
+	assert.True(t, strings.HasPrefix(raw, key.Prefix+"_"))
+	assert.Equal(t, HashApiKey(raw), stored.Hash)
+	assert.NotContains(t, stored.Hash, raw)
+	assert.NotNil(t, key.ExpiresAt)
+
+	_, _, err = keyService.Issue(context.Background(), "hr-bot", []string{"coins:steal"}, 0, 1)
+	assert.Error(t, err)
+
+	_, _, err = keyService.Issue(context.Background(), "hr-bot", nil, 0, 1)
+	assert.Error(t, err)
+
+	mockApiKeyRepo.AssertNumberOfCalls(t, "Create", 1)
+}
+
+func TestApiKeyService_Authenticate(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name      string
+		raw       string
+		key       *domain.ApiKey
+		expectErr bool
+	}{
+		{
+			name: "Valid Key",
+			raw:  "mk_abcd_valid",
+			key:  &domain.ApiKey{Id: 1, ExpiresAt: &future},
+		},
+		{
+			name:      "Unknown Key",
+			raw:       "mk_abcd_unknown",
+			key:       nil,
+			expectErr: true,
+		},
+		{
+			name:      "Expired Key",
+			raw:       "mk_abcd_expired",
+			key:       &domain.ApiKey{Id: 2, ExpiresAt: &past},
+			expectErr: true,
+		},
+		{
+			name:      "Revoked Key",
+			raw:       "mk_abcd_revoked",
+			key:       &domain.ApiKey{Id: 3, RevokedAt: &past},
+			expectErr: true,
+		},
+		{
+			name:      "Wrong Prefix",
+			raw:       "jwt-token",
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockApiKeyRepo := new(mocks.MockApiKeyRepo)
+			keyService := NewApiKeyService(mockApiKeyRepo)
+
+			if strings.HasPrefix(tt.raw, API_KEY_PREFIX) {
+				mockApiKeyRepo.On("GetByHash", mock.Anything, HashApiKey(tt.raw)).Return(tt.key, nil)
+			}
+			if !tt.expectErr {
+				mockApiKeyRepo.On("Touch", mock.Anything, tt.key.Id).Return(nil)
+			}
+
+			key, err := keyService.Authenticate(context.Background(), tt.raw)
+			if tt.expectErr {
+				assert.Error(t, err)
+				assert.Nil(t, key)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.key, key)
+			}
+
+			mockApiKeyRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUserService_LoginTwoFactor(t *testing.T) {
+	setup()
+
+	user := &domain.User{Id: 1, Email: "user@example.com", Password: "hash", Role: domain.ROLE_USER}
+	data := domain.AuthorizationData{Username: "user@example.com", Password: "hash"}
+	expected := domain.AuthorizationData{Id: 1, Username: "user@example.com", Password: "hash", Role: domain.ROLE_USER}
+	var token domain.Token = "token"
+	var challenge domain.Token = "challenge"
+
+	// Без двухфакторной аутентификации сразу выдается токен доступа
+	mockUserRepo.On("GetByEmail", mock.Anything, data.Username).Return(user, nil)
+	mockTwoFactorRepo.On("Get", mock.Anything, user.Id).Return((*domain.TwoFactor)(nil), nil).Once()
+	mockAuthRepo.On("CreateToken", mock.Anything, expected).Return(&token, nil)
+
+	result, err := userService.Login(context.Background(), data)
+	assert.NoError(t, err)
+	assert.Equal(t, &domain.LoginResult{Token: token}, result)
+
+	// С включенной двухфакторной аутентификацией выдается только токен подтверждения
+	mockTwoFactorRepo.On("Get", mock.Anything, user.Id).Return(&domain.TwoFactor{UserId: 1, Enabled: true}, nil).Once()
+	mockAuthRepo.On("CreateChallenge", expected).Return(&challenge, nil)
+
+	result, err = userService.Login(context.Background(), data)
+	assert.NoError(t, err)
+	assert.Equal(t, &domain.LoginResult{Challenge: challenge}, result)
+
+	mockUserRepo.AssertExpectations(t)
+	mockAuthRepo.AssertExpectations(t)
+	mockTwoFactorRepo.AssertExpectations(t)
+	mockAuthRepo.AssertNumberOfCalls(t, "CreateToken", 1)
+}
+
+func TestTwoFactorService_EnrollAndConfirm(t *testing.T) {
+	mockTwoFactorRepo := new(mocks.MockTwoFactorRepo)
+	mockOTPRepo := new(mocks.MockOTPRepo)
+	twoFactorService := NewTwoFactorService(mockTwoFactorRepo, mockOTPRepo, new(mocks.MockAuthRepo))
+
+	mockTwoFactorRepo.On("Get", mock.Anything, uint64(1)).Return((*domain.TwoFactor)(nil), nil).Once()
+	mockOTPRepo.On("GenerateSecret").Return("SECRET", nil)
+	mockOTPRepo.On("URI", "SECRET", "user@example.com").Return("otpauth://totp/uri")
+	mockTwoFactorRepo.On("SetSecret", mock.Anything, uint64(1), "SECRET").Return(nil)
+
+	enrollment, err := twoFactorService.Enroll(context.Background(), 1, "user@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, &domain.TwoFactorEnrollment{Secret: "SECRET", URI: "otpauth://totp/uri"}, enrollment)
+
+	pending := &domain.TwoFactor{UserId: 1, Secret: "SECRET"}
+	mockTwoFactorRepo.On("Get", mock.Anything, uint64(1)).Return(pending, nil)
+	mockOTPRepo.On("Verify", "SECRET", "000000", mock.Anything).Return(int64(0), false).Once()
+
+	_, err = twoFactorService.Confirm(context.Background(), 1, "000000")
+	assert.Error(t, err)
+
+	var hashes []string
+	mockOTPRepo.On("Verify", "SECRET", "123456", mock.Anything).Return(int64(42), true)
+	mockTwoFactorRepo.On("UseStep", mock.Anything, uint64(1), int64(42)).Return(true, nil)
+	mockTwoFactorRepo.On("Enable", mock.Anything, uint64(1), mock.Anything).Run(func(args mock.Arguments) {
+		hashes = args.Get(2).([]string)
+	}).Return(nil)
+
+	codes, err := twoFactorService.Confirm(context.Background(), 1, "123456")
+	assert.NoError(t, err)
+	assert.Len(t, codes, RECOVERY_CODES)
+	assert.Len(t, hashes, RECOVERY_CODES)
+	for i, code := range codes {
+		assert.Len(t, code, RECOVERY_CODE_LENGTH+1)
+		assert.Equal(t, HashRecoveryCode(code), hashes[i])
+	}
+
+	mockTwoFactorRepo.AssertExpectations(t)
+	mockOTPRepo.AssertExpectations(t)
+}
+
+func TestTwoFactorService_Complete(t *testing.T) {
+	mockTwoFactorRepo := new(mocks.MockTwoFactorRepo)
+	mockOTPRepo := new(mocks.MockOTPRepo)
+	mockAuthRepo := new(mocks.MockAuthRepo)
+	twoFactorService := NewTwoFactorService(mockTwoFactorRepo, mockOTPRepo, mockAuthRepo)
+
+	claims := &domain.AuthorizationToken{Id: 1, Email: "user@example.com", Role: domain.ROLE_USER}
+	var token domain.Token = "token"
+
+	mockTwoFactorRepo.On("Get", mock.Anything, uint64(1)).Return(&domain.TwoFactor{UserId: 1, Secret: "SECRET", Enabled: true}, nil)
+	mockAuthRepo.On("CreateToken", mock.Anything, domain.AuthorizationData{Id: 1, Username: "user@example.com", Role: domain.ROLE_USER}).Return(&token, nil)
+
+	// Код из приложения
+	mockOTPRepo.On("Verify", "SECRET", "123456", mock.Anything).Return(int64(42), true)
+	mockTwoFactorRepo.On("UseStep", mock.Anything, uint64(1), int64(42)).Return(true, nil).Once()
+
+	result, err := twoFactorService.Complete(context.Background(), claims, "123456")
+	assert.NoError(t, err)
+	assert.Equal(t, &token, result)
+
+	// Повторное использование того же кода
+	mockTwoFactorRepo.On("UseStep", mock.Anything, uint64(1), int64(42)).Return(false, nil).Once()
+
+	_, err = twoFactorService.Complete(context.Background(), claims, "123456")
+	assert.Error(t, err)
+	assert.IsType(t, &TwoFactorError{}, err)
+	assert.ErrorIs(t, err, domain.ErrUnauthorized)
+
+	// Резервный код
+	mockOTPRepo.On("Verify", "SECRET", "ABCDE-FGHJK", mock.Anything).Return(int64(0), false)
+	mockTwoFactorRepo.On("UseRecoveryCode", mock.Anything, uint64(1), HashRecoveryCode("abcdefghjk")).Return(true, nil)
+
+	result, err = twoFactorService.Complete(context.Background(), claims, "ABCDE-FGHJK")
+	assert.NoError(t, err)
+	assert.Equal(t, &token, result)
+
+	mockTwoFactorRepo.AssertExpectations(t)
+	mockAuthRepo.AssertNumberOfCalls(t, "CreateToken", 2)
+}
+
+func setupIdentity() (*IdentityService, *mocks.MockIdentityProvider, *mocks.MockExternalIdentityRepo) {
+	setup()
+
+	mockProvider := new(mocks.MockIdentityProvider)
+	mockProvider.On("Name").Return("corp")
+	mockIdentityRepo := new(mocks.MockExternalIdentityRepo)
+	mockTwoFactorRepo.On("Get", mock.Anything, mock.Anything).Return((*domain.TwoFactor)(nil), nil)
+
+	return NewIdentityService(mockIdentityRepo, mockUserRepo, mockAuthRepo, mockTwoFactorRepo, mockProvider), mockProvider, mockIdentityRepo
+}
+
+func TestIdentityService_Begin(t *testing.T) {
+	identityService, mockProvider, _ := setupIdentity()
+	mockProvider.On("AuthCodeURL", mock.Anything, mock.Anything).Return("https://idp/authorize")
+
+	login, err := identityService.Begin("corp")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://idp/authorize", login.URL)
+	assert.Len(t, login.State, 32)
+	assert.Len(t, login.Nonce, 32)
+	assert.NotEqual(t, login.State, login.Nonce)
+	mockProvider.AssertCalled(t, "AuthCodeURL", login.State, login.Nonce)
+
+	_, err = identityService.Begin("unknown")
+	assert.Error(t, err)
+	assert.IsType(t, &UnknownProvider{}, err)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func TestIdentityService_CompleteLinked(t *testing.T) {
+	identityService, mockProvider, mockIdentityRepo := setupIdentity()
+	var userId domain.UserId = 7
+	var token domain.Token = "token"
+
+	mockProvider.On("Exchange", mock.Anything, "code", "nonce").Return(&domain.ExternalIdentity{Provider: "corp", Subject: "sub"}, nil)
+	mockIdentityRepo.On("GetUserId", mock.Anything, "corp", "sub").Return(&userId, nil)
+	mockUserRepo.On("GetById", mock.Anything, userId).Return(&domain.User{Id: 7, Email: "user@example.com", Role: domain.ROLE_ADMIN}, nil)
+	mockAuthRepo.On("CreateToken", mock.Anything, domain.AuthorizationData{Id: 7, Username: "user@example.com", Role: domain.ROLE_ADMIN}).Return(&token, nil)
+
+	result, err := identityService.Complete(context.Background(), "corp", "code", "nonce")
+	assert.NoError(t, err)
+	assert.Equal(t, &domain.LoginResult{Token: token}, result)
+	mockIdentityRepo.AssertNotCalled(t, "Link", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestIdentityService_CompleteProvision(t *testing.T) {
+	identityService, mockProvider, mockIdentityRepo := setupIdentity()
+	var userId domain.UserId = 8
+	var token domain.Token = "token"
+
+	mockProvider.On("Exchange", mock.Anything, "code", "nonce").Return(&domain.ExternalIdentity{Provider: "corp", Subject: "sub", Email: "new@example.com", EmailVerified: true}, nil)
+	mockIdentityRepo.On("GetUserId", mock.Anything, "corp", "sub").Return((*domain.UserId)(nil), nil)
+	mockUserRepo.On("GetByEmail", mock.Anything, "new@example.com").Return((*domain.User)(nil), nil)
+	mockUserRepo.On("Create", mock.Anything, mock.MatchedBy(func(user domain.User) bool {
+		return user.Email == "new@example.com" && user.Coins == START_MONEY && user.Role == domain.ROLE_USER && len(user.Password) == 64
+	})).Return(&userId, nil)
+	mockIdentityRepo.On("Link", mock.Anything, "corp", "sub", userId).Return(nil)
+	mockAuthRepo.On("CreateToken", mock.Anything, domain.AuthorizationData{Id: 8, Username: "new@example.com", Role: domain.ROLE_USER}).Return(&token, nil)
+
+	result, err := identityService.Complete(context.Background(), "corp", "code", "nonce")
+	assert.NoError(t, err)
+	assert.Equal(t, &domain.LoginResult{Token: token}, result)
+	mockUserRepo.AssertExpectations(t)
+	mockIdentityRepo.AssertExpectations(t)
+}
+
+func TestIdentityService_CompleteExistingEmail(t *testing.T) {
+	identityService, mockProvider, mockIdentityRepo := setupIdentity()
+	var token domain.Token = "token"
+
+	mockProvider.On("Exchange", mock.Anything, "code", "nonce").Return(&domain.ExternalIdentity{Provider: "corp", Subject: "sub", Email: "user@example.com", EmailVerified: true}, nil)
+	mockIdentityRepo.On("GetUserId", mock.Anything, "corp", "sub").Return((*domain.UserId)(nil), nil)
+	mockUserRepo.On("GetByEmail", mock.Anything, "user@example.com").Return(&domain.User{Id: 3, Email: "user@example.com", Role: domain.ROLE_USER}, nil)
+	mockIdentityRepo.On("Link", mock.Anything, "corp", "sub", domain.UserId(3)).Return(nil)
+	mockAuthRepo.On("CreateToken", mock.Anything, mock.Anything).Return(&token, nil)
+
+	_, err := identityService.Complete(context.Background(), "corp", "code", "nonce")
+	assert.NoError(t, err)
+	mockUserRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	mockIdentityRepo.AssertExpectations(t)
+}
+
+func TestIdentityService_CompleteUnverifiedEmail(t *testing.T) {
+	identityService, mockProvider, mockIdentityRepo := setupIdentity()
+
+	mockProvider.On("Exchange", mock.Anything, "code", "nonce").Return(&domain.ExternalIdentity{Provider: "corp", Subject: "sub", Email: "user@example.com"}, nil)
+	mockIdentityRepo.On("GetUserId", mock.Anything, "corp", "sub").Return((*domain.UserId)(nil), nil)
+
+	_, err := identityService.Complete(context.Background(), "corp", "code", "nonce")
+	assert.Error(t, err)
+	assert.IsType(t, &UnverifiedIdentity{}, err)
+	assert.ErrorIs(t, err, domain.ErrUnauthorized)
+	mockUserRepo.AssertNotCalled(t, "GetByEmail", mock.Anything, mock.Anything)
+	mockIdentityRepo.AssertNotCalled(t, "Link", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestHealthService_Ready(t *testing.T) {
+	tests := []struct {
+		name       string
+		pingErr    error
+		version    uint
+		dirty      bool
+		status     string
+		migrations string
+	}{
+		{"ready", nil, 7, false, domain.HEALTH_OK, domain.HEALTH_OK},
+		{"database down", errors.New("connection refused"), 0, false, domain.HEALTH_FAIL, domain.HEALTH_FAIL},
+		{"old schema", nil, 6, false, domain.HEALTH_FAIL, domain.HEALTH_FAIL},
+		{"dirty schema", nil, 7, true, domain.HEALTH_FAIL, domain.HEALTH_FAIL},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockHealthRepo := new(mocks.MockHealthRepo)
+			mockHealthRepo.On("Ping", mock.Anything).Return(tt.pingErr)
+			mockHealthRepo.On("SchemaVersion", mock.Anything).Return(tt.version, tt.dirty, nil)
+
+			report := NewHealthService(mockHealthRepo, 7).Ready(context.Background())
+			assert.Equal(t, tt.status, report.Status)
+			assert.Equal(t, tt.migrations, report.Checks[CHECK_MIGRATIONS].Status)
+			if tt.pingErr != nil {
+				assert.Equal(t, "connection refused", report.Checks[CHECK_DATABASE].Error)
+				mockHealthRepo.AssertNotCalled(t, "SchemaVersion", mock.Anything)
+			}
+		})
+	}
+}
+
+func TestServices_Metrics(t *testing.T) {
+	setup()
+	mockMetricsRepo.ExpectedCalls = nil
+	mockMetricsRepo.On("ItemPurchased", "t-shirt").Once()
+	mockMetricsRepo.On("CoinsTransferred", 100).Once()
+	mockMetricsRepo.On("LoginFailed", LOGIN_FAILED_PASSWORD).Once()
+
+	item := domain.Item{Name: "t-shirt", Cost: 100}
+	mockInventoryRepo.On("GetSubjectByName", mock.Anything, "t-shirt").Return(&item, nil)
+	mockUserRepo.On("GetById", mock.Anything, uint64(1)).Return(&domain.User{Id: 1, Coins: 200}, nil)
+	mockInventoryRepo.On("Buy", mock.Anything, uint64(1), item).Return(nil)
+	assert.NoError(t, moneyService.BuyMerch(context.Background(), domain.Inventory{Subject: "t-shirt", UserId: 1}))
+
+	transfer := domain.Transaction{SenderName: "sender@example.com", Amount: 100}
+	failed := domain.Transaction{SenderName: "sender@example.com", Amount: 50}
+	mockUserRepo.On("GetByEmail", mock.Anything, "sender@example.com").Return(&domain.User{Email: "sender@example.com", Coins: 200}, nil)
+	mockTransactionRepo.On("Transfer", mock.Anything, transfer).Return(nil)
+	mockTransactionRepo.On("Transfer", mock.Anything, failed).Return(errors.New("database error"))
+	assert.NoError(t, moneyService.MoneyTransfer(context.Background(), transfer))
+	// Неудачный перевод не учитывается
+	assert.Error(t, moneyService.MoneyTransfer(context.Background(), failed))
+
+	mockUserRepo.On("GetByEmail", mock.Anything, "user@example.com").Return(&domain.User{Email: "user@example.com", Password: "correct"}, nil)
+	_, err := userService.Login(context.Background(), domain.AuthorizationData{Username: "user@example.com", Password: "wrong"})
+	assert.Error(t, err)
+
+	mockMetricsRepo.AssertExpectations(t)
+}
+
+func TestUserService_GetInfoSpans(t *testing.T) {
+	setup()
+	recorder := tracetest.NewSpanRecorder()
+	userService = NewUserService(mockUserRepo, mockAuthRepo, mockUserInfoRepo, mockTwoFactorRepo, mockMetricsRepo, sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	// Запросы к репозиториям получают контекст спана сервиса
+	var repoSpan trace.SpanContext
+	mockUserInfoRepo.On("GetInfo", mock.Anything, uint64(1)).Run(func(args mock.Arguments) {
+		repoSpan = trace.SpanContextFromContext(args.Get(0).(context.Context))
+	}).Return((*domain.UserInfo)(nil), errors.New("query error"))
+
+	_, err := userService.GetInfo(context.Background(), 1)
+	assert.Error(t, err)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "UserService.GetInfo", spans[0].Name())
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Equal(t, "query error", spans[0].Status().Description)
+	assert.Equal(t, spans[0].SpanContext().SpanID(), repoSpan.SpanID())
+}
@@ -0,0 +1,19 @@
+package services
+
+import (
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TRACER_NAME - имя трассировщика сервисов
+const TRACER_NAME = "merch/internal/services"
+
+// endSpan завершает спан и, если метод вернул ошибку, помечает спан ошибочным
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
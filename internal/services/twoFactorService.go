@@ -0,0 +1,229 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"merch/internal/domain"
+	"merch/internal/interfaces"
+	"strings"
+	"time"
+)
+
+// Параметры резервных кодов
+const (
+	RECOVERY_CODES         = 10                                // Количество выдаваемых резервных кодов
+	RECOVERY_CODE_LENGTH   = 10                                // Длина кода без разделителя
+	RECOVERY_CODE_ALPHABET = "abcdefghjkmnpqrstuvwxyz23456789" // Алфавит без похожих символов
+)
+
+// TwoFactorError - ошибка подключения или проверки двухфакторной аутентификации
+type TwoFactorError = domain.TypedError[twoFactorError]
+
+type twoFactorError struct{}
+
+// TwoFactorService предоставляет методы для двухфакторной аутентификации по TOTP
+type TwoFactorService struct {
+	twoFactor interfaces.TwoFactorRepo
+	otp       interfaces.OTPRepo
+	auth      interfaces.AuthRepo
+}
+
+// NewTwoFactorService создает новый экземпляр TwoFactorService
+func NewTwoFactorService(twoFactor interfaces.TwoFactorRepo, otp interfaces.OTPRepo, auth interfaces.AuthRepo) *TwoFactorService {
+	return &TwoFactorService{
+		twoFactor: twoFactor,
+		otp:       otp,
+		auth:      auth,
+	}
+}
+
+// Enroll создает новый секрет. Двухфакторная аутентификация включится только после подтверждения кодом
+func (s *TwoFactorService) Enroll(ctx context.Context, userId domain.UserId, email domain.UserEmail) (*domain.TwoFactorEnrollment, error) {
+	twoFactor, err := s.twoFactor.Get(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if twoFactor != nil && twoFactor.Enabled {
+		return nil, &TwoFactorError{
+			Kind: domain.ErrInvalidInput,
+			Err:  "Two-factor authentication is already enabled",
+		}
+	}
+
+	secret, err := s.otp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.twoFactor.SetSecret(ctx, userId, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.TwoFactorEnrollment{
+		Secret: secret,
+		URI:    s.otp.URI(secret, email),
+	}, nil
+}
+
+// Confirm проверяет первый код из приложения, включает двухфакторную аутентификацию и возвращает резервные коды
+func (s *TwoFactorService) Confirm(ctx context.Context, userId domain.UserId, code string) ([]string, error) {
+	twoFactor, err := s.twoFactor.Get(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if twoFactor == nil || twoFactor.Enabled {
+		return nil, &TwoFactorError{
+			Kind: domain.ErrInvalidInput,
+			Err:  "Two-factor authentication is not being enrolled",
+		}
+	}
+
+	step, ok := s.otp.Verify(twoFactor.Secret, code, time.Now())
+	if !ok {
+		return nil, &TwoFactorError{
+			Kind: domain.ErrInvalidInput,
+			Err:  "Invalid code",
+		}
+	}
+
+	_, err = s.twoFactor.UseStep(ctx, userId, step)
+	if err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, RECOVERY_CODES)
+	hashes := make([]string, RECOVERY_CODES)
+	for i := range codes {
+		codes[i], err = generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		hashes[i] = HashRecoveryCode(codes[i])
+	}
+
+	err = s.twoFactor.Enable(ctx, userId, hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// Disable отключает двухфакторную аутентификацию после проверки кода или резервного кода
+func (s *TwoFactorService) Disable(ctx context.Context, userId domain.UserId, code string) error {
+	twoFactor, err := s.twoFactor.Get(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	if twoFactor == nil || !twoFactor.Enabled {
+		return &TwoFactorError{
+			Kind: domain.ErrInvalidInput,
+			Err:  "Two-factor authentication is not enabled",
+		}
+	}
+
+	ok, err := s.verify(ctx, twoFactor, code)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return &TwoFactorError{
+			Kind: domain.ErrInvalidInput,
+			Err:  "Invalid code",
+		}
+	}
+
+	return s.twoFactor.Disable(ctx, userId)
+}
+
+// Challenge декодирует токен подтверждения, выданный на первом шаге входа
+func (s *TwoFactorService) Challenge(challenge domain.Token) (*domain.AuthorizationToken, error) {
+	return s.auth.DecodeChallenge(challenge)
+}
+
+// Complete завершает вход: проверяет код или резервный код и выдает токен доступа
+func (s *TwoFactorService) Complete(ctx context.Context, claims *domain.AuthorizationToken, code string) (*domain.Token, error) {
+	twoFactor, err := s.twoFactor.Get(ctx, claims.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	if twoFactor == nil || !twoFactor.Enabled {
+		return nil, &TwoFactorError{
+			Kind: domain.ErrUnauthorized,
+			Err:  "Two-factor authentication is not enabled",
+		}
+	}
+
+	ok, err := s.verify(ctx, twoFactor, code)
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		return nil, &TwoFactorError{
+			Kind: domain.ErrUnauthorized,
+			Err:  "Invalid code",
+		}
+	}
+
+	return s.auth.CreateToken(ctx, domain.AuthorizationData{
+		Id:       claims.Id,
+		Username: claims.Email,
+		Role:     claims.Role,
+	})
+}
+
+// verify проверяет код из приложения, а если он не подошел - резервный код.
+// Каждый код можно использовать только один раз
+func (s *TwoFactorService) verify(ctx context.Context, twoFactor *domain.TwoFactor, code string) (bool, error) {
+	step, ok := s.otp.Verify(twoFactor.Secret, code, time.Now())
+	if ok {
+		return s.twoFactor.UseStep(ctx, twoFactor.UserId, step)
+	}
+
+	return s.twoFactor.UseRecoveryCode(ctx, twoFactor.UserId, HashRecoveryCode(code))
+}
+
+// HashRecoveryCode возвращает SHA-256 хэш резервного кода без учета регистра и разделителей
+func HashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	hash := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(hash[:])
+}
+
+// generateRecoveryCode создает резервный код вида xxxxx-xxxxx
+func generateRecoveryCode() (string, error) {
+	// Байты, не помещающиеся в целое число алфавитов, отбрасываются, чтобы символы были равновероятны
+	limit := byte(256 / len(RECOVERY_CODE_ALPHABET) * len(RECOVERY_CODE_ALPHABET))
+
+	var code strings.Builder
+	buf := make([]byte, 1)
+	for code.Len() < RECOVERY_CODE_LENGTH+1 {
+		_, err := rand.Read(buf)
+		if err != nil {
+			return "", &TwoFactorError{
+				Kind: domain.ErrInternal,
+				Err:  "Generating recovery code error: " + err.Error(),
+			}
+		}
+
+		if buf[0] >= limit {
+			continue
+		}
+
+		if code.Len() == RECOVERY_CODE_LENGTH/2 {
+			code.WriteByte('-')
+		}
+		code.WriteByte(RECOVERY_CODE_ALPHABET[int(buf[0])%len(RECOVERY_CODE_ALPHABET)])
+	}
+
+	return code.String(), nil
+}
@@ -1,37 +1,65 @@
 package services
 
 import (
+	"context"
 	"merch/internal/domain"
 	"merch/internal/interfaces"
-	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const START_MONEY = 1000
 
+// Причины неудачного входа для метрик
+const (
+	LOGIN_FAILED_PASSWORD = "password"
+)
+
 // InvalidPassword - ошибка неверного пароля
-type InvalidPassword = domain.BaseError
+type InvalidPassword = domain.TypedError[invalidPasswordError]
+
+type invalidPasswordError struct{}
+
+// UserNotFound - ошибка отсутствия пользователя
+type UserNotFound = domain.TypedError[userNotFoundError]
+
+type userNotFoundError struct{}
+
+// InvalidRole - ошибка неизвестной роли
+type InvalidRole = domain.TypedError[invalidRoleError]
+
+type invalidRoleError struct{}
 
 // UserService предоставляет методы для работы с пользователями
 type UserService struct {
-	user        interfaces.UserRepo
-	auth        interfaces.AuthRepo
-	transaction interfaces.TransactionRepo
-	inventory   interfaces.InventoryRepo
+	user      interfaces.UserRepo
+	auth      interfaces.AuthRepo
+	info      interfaces.UserInfoRepo
+	twoFactor interfaces.TwoFactorRepo
+	metrics   interfaces.MetricsRepo
+	tracer    trace.Tracer
 }
 
 // NewUserService создает новый экземпляр UserService
-func NewUserService(user interfaces.UserRepo, auth interfaces.AuthRepo, transaction interfaces.TransactionRepo, inventory interfaces.InventoryRepo) *UserService {
+func NewUserService(user interfaces.UserRepo, auth interfaces.AuthRepo, info interfaces.UserInfoRepo, twoFactor interfaces.TwoFactorRepo, metrics interfaces.MetricsRepo, tracer trace.TracerProvider) *UserService {
 	return &UserService{
-		user:        user,
-		auth:        auth,
-		transaction: transaction,
-		inventory:   inventory,
+		user:      user,
+		auth:      auth,
+		info:      info,
+		twoFactor: twoFactor,
+		metrics:   metrics,
+		tracer:    tracer.Tracer(TRACER_NAME),
 	}
 }
 
-// Login выполняет авторизацию пользователя
-func (s *UserService) Login(data domain.AuthorizationData) (*domain.Token, error) {
-	user, err := s.user.GetByEmail(data.Username)
+// Login выполняет авторизацию пользователя. Если у пользователя включена двухфакторная аутентификация,
+// вместо токена доступа возвращается токен подтверждения для второго шага
+func (s *UserService) Login(ctx context.Context, data domain.AuthorizationData) (result *domain.LoginResult, err error) {
+	ctx, span := s.tracer.Start(ctx, "UserService.Login")
+	defer func() { endSpan(span, err) }()
+
+	user, err := s.user.GetByEmail(ctx, data.Username)
 	if err != nil {
 		return nil, err
 	}
@@ -39,56 +67,134 @@ func (s *UserService) Login(data domain.AuthorizationData) (*domain.Token, error
 	if user == nil {
 		// Создание нового пользователя, если пользователь не найден
 		user := domain.CreateUser(data.Username, data.Password, START_MONEY)
-		id, err := s.user.Create(*user)
+		id, err := s.user.Create(ctx, *user)
 		if err != nil {
 			return nil, err
 		}
 
 		data.Id = *id
+		data.Role = user.Role
 	} else {
 		// Проверка пароля
 		if user.Password != data.Password {
+			s.metrics.LoginFailed(LOGIN_FAILED_PASSWORD)
 			return nil, &InvalidPassword{
-				Code: http.StatusUnauthorized,
+				Kind: domain.ErrUnauthorized,
 				Err:  "Invalid password or email",
 			}
 		}
 
 		data.Id = user.Id
+		data.Role = user.Role
+	}
+
+	return startSession(ctx, s.auth, s.twoFactor, data)
+}
+
+// startSession выдает токен доступа, а если у пользователя включена двухфакторная аутентификация - токен подтверждения
+func startSession(ctx context.Context, auth interfaces.AuthRepo, twoFactorRepo interfaces.TwoFactorRepo, data domain.AuthorizationData) (*domain.LoginResult, error) {
+	twoFactor, err := twoFactorRepo.Get(ctx, data.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	if twoFactor != nil && twoFactor.Enabled {
+		challenge, err := auth.CreateChallenge(data)
+		if err != nil {
+			return nil, err
+		}
+
+		return &domain.LoginResult{Challenge: *challenge}, nil
 	}
 
 	// Создание токена авторизации
-	tokens, err := s.auth.CreateToken(data)
+	token, err := auth.CreateToken(ctx, data)
 	if err != nil {
 		return nil, err
 	}
 
-	return tokens, nil
+	return &domain.LoginResult{Token: *token}, nil
 }
 
 // GetInfo получает информацию о пользователе по его идентификатору
-func (s *UserService) GetInfo(userId uint64) (*domain.UserInfo, error) {
-	user, err := s.user.GetById(userId)
+func (s *UserService) GetInfo(ctx context.Context, userId uint64) (info *domain.UserInfo, err error) {
+	ctx, span := s.tracer.Start(ctx, "UserService.GetInfo", trace.WithAttributes(attribute.Int64("merch.user_id", int64(userId))))
+	defer func() { endSpan(span, err) }()
+
+	// Баланс, инвентарь и история переводов читаются одним запросом
+	return s.info.GetInfo(ctx, userId)
+}
+
+// GetInfoByEmail получает информацию о пользователе по его email
+func (s *UserService) GetInfoByEmail(ctx context.Context, email domain.UserEmail) (info *domain.UserInfo, err error) {
+	ctx, span := s.tracer.Start(ctx, "UserService.GetInfoByEmail")
+	defer func() { endSpan(span, err) }()
 
+	user, err := s.user.GetByEmail(ctx, email)
 	if err != nil {
 		return nil, err
 	}
 
-	transactions, err := s.transaction.GetTransaction(user.Email)
+	if user == nil {
+		return nil, &UserNotFound{
+			Kind: domain.ErrInvalidInput,
+			Err:  "User not exists",
+		}
+	}
+
+	return s.GetInfo(ctx, user.Id)
+}
+
+// GetBalance возвращает количество монет пользователя по его email
+func (s *UserService) GetBalance(ctx context.Context, email domain.UserEmail) (balance domain.Amount, err error) {
+	ctx, span := s.tracer.Start(ctx, "UserService.GetBalance")
+	defer func() { endSpan(span, err) }()
+
+	user, err := s.user.GetByEmail(ctx, email)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
 
-	inventory, err := s.inventory.GetInventory(userId)
+	if user == nil {
+		return 0, &UserNotFound{
+			Kind: domain.ErrInvalidInput,
+			Err:  "User not exists",
+		}
+	}
+
+	return user.Coins, nil
+}
+
+// SetRole изменяет роль пользователя и отзывает его токены, чтобы новая роль вступила в силу при следующем входе
+func (s *UserService) SetRole(ctx context.Context, email domain.UserEmail, role domain.Role) (err error) {
+	ctx, span := s.tracer.Start(ctx, "UserService.SetRole", trace.WithAttributes(attribute.String("merch.role", role)))
+	defer func() { endSpan(span, err) }()
+
+	if !domain.IsValidRole(role) {
+		return &InvalidRole{
+			Kind: domain.ErrInvalidInput,
+			Err:  "Unknown role",
+		}
+	}
+
+	user, err := s.user.GetByEmail(ctx, email)
 	if err != nil {
-		return nil, err
+		return err
+	}
+
+	if user == nil {
+		return &UserNotFound{
+			Kind: domain.ErrInvalidInput,
+			Err:  "User not exists",
+		}
+	}
+
+	err = s.user.SetRole(ctx, email, role)
+	if err != nil {
+		return err
 	}
 
-	return &domain.UserInfo{
-		Coins:        user.Coins,
-		Inventory:    *inventory,
-		Transactions: *transactions,
-	}, nil
+	return s.auth.Revoke(ctx, user.Id)
 }
 
 // Token декодирует переданный токен и возвращает информацию о нем
@@ -97,6 +203,6 @@ func (s *UserService) Token(token domain.Token) (*domain.AuthorizationToken, err
 }
 
 // Access проверяет не был ли токен доступа отозван
-func (s *UserService) Access(token domain.Token, userId domain.UserId) (*domain.SuccessfulAuth, error) {
-	return s.auth.Access(token, userId)
+func (s *UserService) Access(ctx context.Context, token domain.Token, userId domain.UserId) (*domain.SuccessfulAuth, error) {
+	return s.auth.Access(ctx, token, userId)
 }
@@ -0,0 +1,38 @@
+package mocks
+
+import (
+	"context"
+	"merch/internal/domain"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockApiKeyRepo - мок-объект для интерфейса ApiKeyRepo
+type MockApiKeyRepo struct {
+	mock.Mock
+}
+
+func (m *MockApiKeyRepo) Create(ctx context.Context, key domain.ApiKey) (*uint64, error) {
+	args := m.Called(ctx, key)
+	return args.Get(0).(*uint64), args.Error(1)
+}
+
+func (m *MockApiKeyRepo) GetByHash(ctx context.Context, hash string) (*domain.ApiKey, error) {
+	args := m.Called(ctx, hash)
+	return args.Get(0).(*domain.ApiKey), args.Error(1)
+}
+
+func (m *MockApiKeyRepo) List(ctx context.Context) ([]domain.ApiKey, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]domain.ApiKey), args.Error(1)
+}
+
+func (m *MockApiKeyRepo) Touch(ctx context.Context, id uint64) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockApiKeyRepo) Revoke(ctx context.Context, id uint64) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
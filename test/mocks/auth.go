@@ -1,6 +1,7 @@
 package mocks
 
 import (
+	"context"
 	"merch/internal/domain"
 
 	"github.com/stretchr/testify/mock"
@@ -11,8 +12,8 @@ type MockAuthRepo struct {
 	mock.Mock
 }
 
-func (m *MockAuthRepo) CreateToken(data domain.AuthorizationData) (*domain.Token, error) {
-	args := m.Called(data)
+func (m *MockAuthRepo) CreateToken(ctx context.Context, data domain.AuthorizationData) (*domain.Token, error) {
+	args := m.Called(ctx, data)
 	return args.Get(0).(*domain.Token), args.Error(1)
 }
 
@@ -21,7 +22,22 @@ func (m *MockAuthRepo) DecodeToken(token domain.Token) (*domain.AuthorizationTok
 	return args.Get(0).(*domain.AuthorizationToken), args.Error(1)
 }
 
-func (m *MockAuthRepo) Access(token domain.Token, userId domain.UserId) (*domain.SuccessfulAuth, error) {
-	args := m.Called(token, userId)
+func (m *MockAuthRepo) Access(ctx context.Context, token domain.Token, userId domain.UserId) (*domain.SuccessfulAuth, error) {
+	args := m.Called(ctx, token, userId)
 	return args.Get(0).(*domain.SuccessfulAuth), args.Error(1)
 }
+
+func (m *MockAuthRepo) Revoke(ctx context.Context, userId domain.UserId) error {
+	args := m.Called(ctx, userId)
+	return args.Error(0)
+}
+
+func (m *MockAuthRepo) CreateChallenge(data domain.AuthorizationData) (*domain.Token, error) {
+	args := m.Called(data)
+	return args.Get(0).(*domain.Token), args.Error(1)
+}
+
+func (m *MockAuthRepo) DecodeChallenge(token domain.Token) (*domain.AuthorizationToken, error) {
+	args := m.Called(token)
+	return args.Get(0).(*domain.AuthorizationToken), args.Error(1)
+}
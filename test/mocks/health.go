@@ -0,0 +1,22 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockHealthRepo - мок-объект для интерфейса HealthRepo
+type MockHealthRepo struct {
+	mock.Mock
+}
+
+func (m *MockHealthRepo) Ping(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+func (m *MockHealthRepo) SchemaVersion(ctx context.Context) (uint, bool, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(uint), args.Bool(1), args.Error(2)
+}
@@ -0,0 +1,43 @@
+package mocks
+
+import (
+	"context"
+	"merch/internal/domain"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockIdentityProvider - мок-объект для интерфейса IdentityProvider
+type MockIdentityProvider struct {
+	mock.Mock
+}
+
+func (m *MockIdentityProvider) Name() string {
+	args := m.Called()
+	return args.String(0)
+}
+
+func (m *MockIdentityProvider) AuthCodeURL(state, nonce string) string {
+	args := m.Called(state, nonce)
+	return args.String(0)
+}
+
+func (m *MockIdentityProvider) Exchange(ctx context.Context, code, nonce string) (*domain.ExternalIdentity, error) {
+	args := m.Called(ctx, code, nonce)
+	return args.Get(0).(*domain.ExternalIdentity), args.Error(1)
+}
+
+// MockExternalIdentityRepo - мок-объект для интерфейса ExternalIdentityRepo
+type MockExternalIdentityRepo struct {
+	mock.Mock
+}
+
+func (m *MockExternalIdentityRepo) GetUserId(ctx context.Context, provider, subject string) (*domain.UserId, error) {
+	args := m.Called(ctx, provider, subject)
+	return args.Get(0).(*domain.UserId), args.Error(1)
+}
+
+func (m *MockExternalIdentityRepo) Link(ctx context.Context, provider, subject string, userId domain.UserId) error {
+	args := m.Called(ctx, provider, subject, userId)
+	return args.Error(0)
+}
@@ -1,6 +1,7 @@
 package mocks
 
 import (
+	"context"
 	"merch/internal/domain"
 
 	"github.com/stretchr/testify/mock"
@@ -11,17 +12,17 @@ type MockInventoryRepo struct {
 	mock.Mock
 }
 
-func (m *MockInventoryRepo) GetSubjectByName(name string) (*domain.Item, error) {
-	args := m.Called(name)
+func (m *MockInventoryRepo) GetSubjectByName(ctx context.Context, name string) (*domain.Item, error) {
+	args := m.Called(ctx, name)
 	return args.Get(0).(*domain.Item), args.Error(1)
 }
 
-func (m *MockInventoryRepo) Buy(userId domain.UserId, subject domain.Item) error {
-	args := m.Called(userId, subject)
+func (m *MockInventoryRepo) Buy(ctx context.Context, userId domain.UserId, subject domain.Item) error {
+	args := m.Called(ctx, userId, subject)
 	return args.Error(0)
 }
 
-func (m *MockInventoryRepo) GetInventory(userId domain.UserId) (*[]domain.Inventory, error) {
-	args := m.Called(userId)
+func (m *MockInventoryRepo) GetInventory(ctx context.Context, userId domain.UserId) (*[]domain.Inventory, error) {
+	args := m.Called(ctx, userId)
 	return args.Get(0).(*[]domain.Inventory), args.Error(1)
 }
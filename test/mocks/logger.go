@@ -1,30 +1,48 @@
 package mocks
 
 import (
+	"merch/internal/domain"
+	"merch/internal/interfaces"
+
 	"github.com/stretchr/testify/mock"
 )
 
-// LoggerRepo - мок-объект для интерфейса LoggerRepo
+// LoggerRepo - мок-объект для интерфейса LoggerRepo.
+// Записи без полей ожидаются как On("Info", msg), с полями - как On("Info", msg, fields)
 type LoggerRepo struct {
 	mock.Mock
 }
 
-func (m *LoggerRepo) Debug(message string) {
-	m.Called(message)
+func (m *LoggerRepo) call(method, message string, fields []domain.LogField) {
+	if len(fields) == 0 {
+		m.MethodCalled(method, message)
+		return
+	}
+
+	m.MethodCalled(method, message, fields)
+}
+
+func (m *LoggerRepo) Debug(message string, fields ...domain.LogField) {
+	m.call("Debug", message, fields)
+}
+
+func (m *LoggerRepo) Info(message string, fields ...domain.LogField) {
+	m.call("Info", message, fields)
 }
 
-func (m *LoggerRepo) Info(message string) {
-	m.Called(message)
+func (m *LoggerRepo) Warn(message string, fields ...domain.LogField) {
+	m.call("Warn", message, fields)
 }
 
-func (m *LoggerRepo) Warn(message string) {
-	m.Called(message)
+func (m *LoggerRepo) Error(message string, fields ...domain.LogField) {
+	m.call("Error", message, fields)
 }
 
-func (m *LoggerRepo) Error(message string) {
-	m.Called(message)
+func (m *LoggerRepo) Fatal(message string, fields ...domain.LogField) {
+	m.call("Fatal", message, fields)
 }
 
-func (m *LoggerRepo) Fatal(message string) {
-	m.Called(message)
+// With возвращает тот же мок, поля дочернего логгера не проверяются
+func (m *LoggerRepo) With(fields ...domain.LogField) interfaces.LoggerRepo {
+	return m
 }
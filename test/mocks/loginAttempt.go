@@ -0,0 +1,34 @@
+package mocks
+
+import (
+	"context"
+	"merch/internal/domain"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockLoginAttemptRepo - мок-объект для интерфейса LoginAttemptRepo
+type MockLoginAttemptRepo struct {
+	mock.Mock
+}
+
+func (m *MockLoginAttemptRepo) Get(ctx context.Context, key string) (*domain.LoginAttempt, error) {
+	args := m.Called(ctx, key)
+	return args.Get(0).(*domain.LoginAttempt), args.Error(1)
+}
+
+func (m *MockLoginAttemptRepo) Fail(ctx context.Context, key string, window time.Duration) (int, error) {
+	args := m.Called(ctx, key, window)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockLoginAttemptRepo) Lock(ctx context.Context, key string, duration time.Duration) error {
+	args := m.Called(ctx, key, duration)
+	return args.Error(0)
+}
+
+func (m *MockLoginAttemptRepo) Reset(ctx context.Context, key string) error {
+	args := m.Called(ctx, key)
+	return args.Error(0)
+}
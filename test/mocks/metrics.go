@@ -0,0 +1,42 @@
+package mocks
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockMetricsRepo - мок-объект для интерфейса MetricsRepo
+type MockMetricsRepo struct {
+	mock.Mock
+}
+
+func (m *MockMetricsRepo) ObserveRequest(method, route string, status int, duration time.Duration) {
+	m.Called(method, route, status, duration)
+}
+
+func (m *MockMetricsRepo) CoinsTransferred(amount int) {
+	m.Called(amount)
+}
+
+func (m *MockMetricsRepo) ItemPurchased(item string) {
+	m.Called(item)
+}
+
+func (m *MockMetricsRepo) LoginFailed(reason string) {
+	m.Called(reason)
+}
+
+func (m *MockMetricsRepo) CacheHit(cache string) {
+	m.Called(cache)
+}
+
+func (m *MockMetricsRepo) CacheMiss(cache string) {
+	m.Called(cache)
+}
+
+func (m *MockMetricsRepo) Handler() http.Handler {
+	args := m.Called()
+	return args.Get(0).(http.Handler)
+}
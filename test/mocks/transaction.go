@@ -1,6 +1,7 @@
 package mocks
 
 import (
+	"context"
 	"merch/internal/domain"
 
 	"github.com/stretchr/testify/mock"
@@ -11,12 +12,12 @@ type MockTransactionRepo struct {
 	mock.Mock
 }
 
-func (m *MockTransactionRepo) Transfer(transaction domain.Transaction) error {
-	args := m.Called(transaction)
+func (m *MockTransactionRepo) Transfer(ctx context.Context, transaction domain.Transaction) error {
+	args := m.Called(ctx, transaction)
 	return args.Error(0)
 }
 
-func (m *MockTransactionRepo) GetTransaction(email domain.UserEmail) (*[]domain.Transaction, error) {
-	args := m.Called(email)
+func (m *MockTransactionRepo) GetTransaction(ctx context.Context, email domain.UserEmail) (*[]domain.Transaction, error) {
+	args := m.Called(ctx, email)
 	return args.Get(0).(*[]domain.Transaction), args.Error(1)
 }
@@ -0,0 +1,64 @@
+package mocks
+
+import (
+	"context"
+	"merch/internal/domain"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockTwoFactorRepo - мок-объект для интерфейса TwoFactorRepo
+type MockTwoFactorRepo struct {
+	mock.Mock
+}
+
+func (m *MockTwoFactorRepo) Get(ctx context.Context, userId domain.UserId) (*domain.TwoFactor, error) {
+	args := m.Called(ctx, userId)
+	return args.Get(0).(*domain.TwoFactor), args.Error(1)
+}
+
+func (m *MockTwoFactorRepo) SetSecret(ctx context.Context, userId domain.UserId, secret string) error {
+	args := m.Called(ctx, userId, secret)
+	return args.Error(0)
+}
+
+func (m *MockTwoFactorRepo) Enable(ctx context.Context, userId domain.UserId, recoveryHashes []string) error {
+	args := m.Called(ctx, userId, recoveryHashes)
+	return args.Error(0)
+}
+
+func (m *MockTwoFactorRepo) Disable(ctx context.Context, userId domain.UserId) error {
+	args := m.Called(ctx, userId)
+	return args.Error(0)
+}
+
+func (m *MockTwoFactorRepo) UseStep(ctx context.Context, userId domain.UserId, step int64) (bool, error) {
+	args := m.Called(ctx, userId, step)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockTwoFactorRepo) UseRecoveryCode(ctx context.Context, userId domain.UserId, hash string) (bool, error) {
+	args := m.Called(ctx, userId, hash)
+	return args.Bool(0), args.Error(1)
+}
+
+// MockOTPRepo - мок-объект для интерфейса OTPRepo
+type MockOTPRepo struct {
+	mock.Mock
+}
+
+func (m *MockOTPRepo) GenerateSecret() (string, error) {
+	args := m.Called()
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockOTPRepo) URI(secret, account string) string {
+	args := m.Called(secret, account)
+	return args.String(0)
+}
+
+func (m *MockOTPRepo) Verify(secret, code string, at time.Time) (int64, bool) {
+	args := m.Called(secret, code, at)
+	return args.Get(0).(int64), args.Bool(1)
+}
@@ -1,6 +1,7 @@
 package mocks
 
 import (
+	"context"
 	"merch/internal/domain"
 
 	"github.com/stretchr/testify/mock"
@@ -11,17 +12,27 @@ type MockUserRepo struct {
 	mock.Mock
 }
 
-func (m *MockUserRepo) Create(user domain.User) (*domain.UserId, error) {
-	args := m.Called(user)
+func (m *MockUserRepo) Create(ctx context.Context, user domain.User) (*domain.UserId, error) {
+	args := m.Called(ctx, user)
 	return args.Get(0).(*domain.UserId), args.Error(1)
 }
 
-func (m *MockUserRepo) GetById(id domain.UserId) (*domain.User, error) {
-	args := m.Called(id)
+func (m *MockUserRepo) GetById(ctx context.Context, id domain.UserId) (*domain.User, error) {
+	args := m.Called(ctx, id)
 	return args.Get(0).(*domain.User), args.Error(1)
 }
 
-func (m *MockUserRepo) GetByEmail(email domain.UserEmail) (*domain.User, error) {
-	args := m.Called(email)
+func (m *MockUserRepo) GetByEmail(ctx context.Context, email domain.UserEmail) (*domain.User, error) {
+	args := m.Called(ctx, email)
 	return args.Get(0).(*domain.User), args.Error(1)
 }
+
+func (m *MockUserRepo) SetRole(ctx context.Context, email domain.UserEmail, role domain.Role) error {
+	args := m.Called(ctx, email, role)
+	return args.Error(0)
+}
+
+func (m *MockUserRepo) AddCoins(ctx context.Context, grant domain.Grant) error {
+	args := m.Called(ctx, grant)
+	return args.Error(0)
+}
@@ -0,0 +1,18 @@
+package mocks
+
+import (
+	"context"
+	"merch/internal/domain"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockUserInfoRepo - мок-объект для интерфейса UserInfoRepo
+type MockUserInfoRepo struct {
+	mock.Mock
+}
+
+func (m *MockUserInfoRepo) GetInfo(ctx context.Context, userId domain.UserId) (*domain.UserInfo, error) {
+	args := m.Called(ctx, userId)
+	return args.Get(0).(*domain.UserInfo), args.Error(1)
+}
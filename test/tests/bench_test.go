@@ -0,0 +1,155 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"merch/internal/domain"
+	"merch/internal/interfaces"
+	"merch/internal/presentation/postgres"
+	"merch/internal/presentation/realization"
+	"merch/internal/services"
+	"testing"
+
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace/noop"
+	"go.uber.org/zap"
+)
+
+// Размер истории пользователя в бенчмарке, близкий к активному сотруднику
+const (
+	BENCH_TRANSACTIONS = 50
+	BENCH_ITEMS        = 20
+)
+
+// BenchmarkDrivers сравнивает GetInfo на прежнем драйвере lib/pq и на pgx с кэшем подготовленных запросов и без него:
+// go test ./test/tests -run '^$' -bench Drivers
+func BenchmarkDrivers(b *testing.B) {
+	benchmarks := []struct {
+		name string
+		open func(logger interfaces.LoggerRepo) (*postgres.DB, error)
+	}{
+		{"lib/pq", func(logger interfaces.LoggerRepo) (*postgres.DB, error) {
+			db, err := sql.Open("postgres", fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable", HOST, PORT, USER, PASS, NAME))
+			if err != nil {
+				return nil, err
+			}
+			return &postgres.DB{Db: db, Logger: logger}, db.Ping()
+		}},
+		{"pgx statement cache", func(logger interfaces.LoggerRepo) (*postgres.DB, error) {
+			return postgres.CreateDB(HOST, PORT, USER, PASS, NAME, domain.DbOptions{}, logger, nil)
+		}},
+		{"pgx no statement cache", func(logger interfaces.LoggerRepo) (*postgres.DB, error) {
+			return postgres.CreateDB(HOST, PORT, USER, PASS, NAME, domain.DbOptions{NoStatementCache: true}, logger, nil)
+		}},
+	}
+
+	for _, bench := range benchmarks {
+		b.Run(bench.name, func(b *testing.B) {
+			logger := realization.NewZapLogger(zap.NewNop())
+			db, err := bench.open(logger)
+			require.NoError(b, err)
+			defer func() { _ = db.CloseDB() }()
+
+			userId := createBenchUser(b, db)
+			userService := services.NewUserService(
+				realization.NewUser(db.Db, db, logger, realization.DEFAULT_QUERY_TIMEOUT),
+				realization.NewAuth(db.Db, logger, realization.DEFAULT_QUERY_TIMEOUT, SECRET),
+				realization.NewUserInfo(db.Db, db, logger, realization.DEFAULT_QUERY_TIMEOUT),
+				realization.NewTwoFactor(db.Db, logger, realization.DEFAULT_QUERY_TIMEOUT),
+				realization.NewMetrics(nil),
+				noop.NewTracerProvider(),
+			)
+
+			runParallel(b, func(ctx context.Context) error {
+				_, err := userService.GetInfo(ctx, userId)
+				return err
+			})
+		})
+	}
+}
+
+// BenchmarkUserInfo сравнивает сводку пользователя одним запросом с тремя последовательными запросами,
+// которыми /api/info собирал ответ раньше: go test ./test/tests -run '^$' -bench UserInfo
+func BenchmarkUserInfo(b *testing.B) {
+	logger := realization.NewZapLogger(zap.NewNop())
+	db, err := postgres.CreateDB(HOST, PORT, USER, PASS, NAME, domain.DbOptions{}, logger, nil)
+	require.NoError(b, err)
+	defer func() { _ = db.CloseDB() }()
+
+	userId := createBenchUser(b, db)
+
+	b.Run("three queries", func(b *testing.B) {
+		userRepo := realization.NewUser(db.Db, db, logger, realization.DEFAULT_QUERY_TIMEOUT)
+		transactionRepo := realization.NewTransaction(db.Db, db, logger, realization.DEFAULT_QUERY_TIMEOUT)
+		inventoryRepo := realization.NewInventory(db.Db, db, logger, realization.DEFAULT_QUERY_TIMEOUT)
+
+		runParallel(b, func(ctx context.Context) error {
+			user, err := userRepo.GetById(ctx, userId)
+			if err != nil {
+				return err
+			}
+
+			_, err = transactionRepo.GetTransaction(ctx, user.Email)
+			if err != nil {
+				return err
+			}
+
+			_, err = inventoryRepo.GetInventory(ctx, userId)
+			return err
+		})
+	})
+
+	b.Run("single query", func(b *testing.B) {
+		userInfoRepo := realization.NewUserInfo(db.Db, db, logger, realization.DEFAULT_QUERY_TIMEOUT)
+
+		runParallel(b, func(ctx context.Context) error {
+			_, err := userInfoRepo.GetInfo(ctx, userId)
+			return err
+		})
+	})
+}
+
+// runParallel выполняет call в параллельных горутинах бенчмарка и останавливается на первой ошибке
+func runParallel(b *testing.B, call func(ctx context.Context) error) {
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			err := call(context.Background())
+			if err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
+
+// createBenchUser создает пользователя с историей переводов и покупок, повторный вызов пересоздает историю
+func createBenchUser(b *testing.B, db *postgres.DB) domain.UserId {
+	ctx := context.Background()
+
+	var userId domain.UserId
+	for _, email := range []string{"bench-peer@example.com", "bench@example.com"} {
+		err := db.Db.QueryRowContext(ctx, `INSERT INTO Users ("email", "password", "coins") VALUES ($1, '', 1000)
+			ON CONFLICT ("email") DO UPDATE SET "coins" = EXCLUDED."coins" RETURNING "id"`, email).Scan(&userId)
+		require.NoError(b, err)
+	}
+
+	_, err := db.Db.ExecContext(ctx, `DELETE FROM Transaction WHERE "sender_name" = 'bench@example.com' OR "receiver_name" = 'bench@example.com'`)
+	require.NoError(b, err)
+	_, err = db.Db.ExecContext(ctx, `DELETE FROM Inventory WHERE "user_id" = $1`, userId)
+	require.NoError(b, err)
+
+	_, err = db.Db.ExecContext(ctx, `INSERT INTO Transaction ("sender_name", "receiver_name", "amount")
+		SELECT CASE WHEN n % 2 = 0 THEN 'bench@example.com' ELSE 'bench-peer@example.com' END,
+			CASE WHEN n % 2 = 0 THEN 'bench-peer@example.com' ELSE 'bench@example.com' END, n
+		FROM generate_series(1, $1::int) AS n`, BENCH_TRANSACTIONS)
+	require.NoError(b, err)
+	_, err = db.Db.ExecContext(ctx, `INSERT INTO Inventory ("subject_name", "user_id")
+		SELECT (ARRAY['t-shirt', 'cup', 'pen'])[n % 3 + 1], $1 FROM generate_series(1, $2::int) AS n`, userId, BENCH_ITEMS)
+	require.NoError(b, err)
+
+	return userId
+}
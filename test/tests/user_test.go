@@ -2,7 +2,10 @@ package tests
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"io/fs"
 	"log"
 	"merch/internal/domain"
 	"merch/internal/presentation/postgres"
@@ -16,8 +19,12 @@ import (
 
 	"github.com/joho/godotenv"
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace/noop"
 )
 
+// TEST_SECRET - ключ подписи JWT для тестов, если SECRET_KEY не задан
+const TEST_SECRET = "integration-tests-secret-key-0123456789"
+
 var (
 	HOST   string
 	PORT   string
@@ -29,8 +36,9 @@ var (
 )
 
 func init() {
+	// .env не хранится в репозитории, без него настройки берутся из окружения
 	err := godotenv.Load("../../.env")
-	if err != nil {
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
 		panic(err)
 	}
 
@@ -40,36 +48,71 @@ func init() {
 	PASS = os.Getenv("DB_PASSWORD")
 	NAME = os.Getenv("DB_NAME")
 	SECRET = os.Getenv("SECRET_KEY")
+	if SECRET == "" {
+		SECRET = TEST_SECRET
+	}
 
 	Srv = StartTestServer()
 }
 
 func StartTestServer() *server.Server {
 	// Настройка логгера
-	logger, err := realization.NewLogger()
+	logger, err := realization.NewLogger("debug", domain.LOG_FORMAT_CONSOLE)
 	if err != nil {
 		log.Fatalf("Could not create logger: %v", err)
 	}
 
 	// Настройка базы данных
-	_, err = postgres.CreateDB(HOST, PORT, USER, PASS, NAME, logger)
+	db, err := postgres.CreateDB(HOST, PORT, USER, PASS, NAME, domain.DbOptions{ConnectAttempts: 5, ConnectBackoff: time.Second}, logger, nil)
 	if err != nil {
 		log.Fatalf("Could not create database connection: %v", err)
 	}
 
-	// Настройка сервисов
-	userRepo := realization.NewUser()
-	authRepo := realization.NewAuth(SECRET)
-	transactionRepo := realization.NewTransaction()
-	inventoryRepo := realization.NewInventory()
+	// Схема и каталог товаров, пользователей тесты создают сами
+	err = db.CreateSchema()
+	if err != nil {
+		log.Fatalf("Could not migrate database: %v", err)
+	}
 
-	userService := services.NewUserService(userRepo, authRepo, transactionRepo, inventoryRepo)
-	moneyService := services.NewMoneyService(userRepo, transactionRepo, inventoryRepo)
+	err = db.Seed(context.Background(), domain.SEED_CATALOG)
+	if err != nil {
+		log.Fatalf("Could not seed database: %v", err)
+	}
+
+	// Настройка сервисов
+	metrics := realization.NewMetrics(db.Pools())
+	userRepo := realization.NewUser(db.Db, db, logger, realization.DEFAULT_QUERY_TIMEOUT)
+	authRepo := realization.NewAuth(db.Db, logger, realization.DEFAULT_QUERY_TIMEOUT, SECRET)
+	transactionRepo := realization.NewTransaction(db.Db, db, logger, realization.DEFAULT_QUERY_TIMEOUT)
+	inventoryRepo := realization.NewInventory(db.Db, db, logger, realization.DEFAULT_QUERY_TIMEOUT)
+	userInfoRepo := realization.NewUserInfo(db.Db, db, logger, realization.DEFAULT_QUERY_TIMEOUT)
+	twoFactorRepo := realization.NewTwoFactor(db.Db, logger, realization.DEFAULT_QUERY_TIMEOUT)
+
+	userService := services.NewUserService(userRepo, authRepo, userInfoRepo, twoFactorRepo, metrics, noop.NewTracerProvider())
+	moneyService := services.NewMoneyService(userRepo, transactionRepo, inventoryRepo, metrics, noop.NewTracerProvider())
+	guardService := services.NewLoginGuardService(realization.NewLoginAttempt(db.Db, logger, realization.DEFAULT_QUERY_TIMEOUT))
+	keyService := services.NewApiKeyService(realization.NewApiKey(db.Db, logger, realization.DEFAULT_QUERY_TIMEOUT))
+	twoFactorService := services.NewTwoFactorService(twoFactorRepo, realization.NewTOTP("Merch"), authRepo)
+	identityService := services.NewIdentityService(realization.NewExternalIdentity(db.Db, logger, realization.DEFAULT_QUERY_TIMEOUT), userRepo, authRepo, twoFactorRepo)
 
 	// Запуск сервера
-	srv := server.NewServer()
+	srv := server.NewServer(server.Deps{
+		Money:     moneyService,
+		User:      userService,
+		Guard:     guardService,
+		Keys:      keyService,
+		TwoFactor: twoFactorService,
+		Identity:  identityService,
+		Health:    services.NewHealthService(db, postgres.SCHEMA_VERSION),
+		Limiter:   realization.NewMemoryRateLimiter(),
+		Limits:    server.DefaultRateLimitConfig(),
+		Metrics:   metrics,
+		Logger:    logger,
+		Db:        db,
+		Port:      "8080",
+	})
 	go func() {
-		if err := srv.Start(moneyService, userService, SECRET, "8080"); err != nil {
+		if err := srv.Start(); err != nil {
 			log.Fatalf("Could not start server: %v", err)
 		}
 	}()